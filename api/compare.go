@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"supafirehose/metrics"
+)
+
+// CompareRequest is the body of POST /api/compare: two summaries previously
+// exported from GET /api/summary
+type CompareRequest struct {
+	A RunMetadata `json:"a"`
+	B RunMetadata `json:"b"`
+}
+
+// OperationComparison sets one operation's averages from two runs side by
+// side, with B's change relative to A (0 when A is 0)
+type OperationComparison struct {
+	A metrics.OperationStats `json:"a"`
+	B metrics.OperationStats `json:"b"`
+
+	QPSChangePct float64 `json:"qps_change_pct"`
+	P50ChangePct float64 `json:"latency_p50_change_pct"`
+	P99ChangePct float64 `json:"latency_p99_change_pct"`
+}
+
+// RunComparison is the response for POST /api/compare and -compare
+type RunComparison struct {
+	Reads      OperationComparison `json:"reads"`
+	Writes     OperationComparison `json:"writes"`
+	ErrorRateA float64             `json:"error_rate_a"`
+	ErrorRateB float64             `json:"error_rate_b"`
+}
+
+// CompareRuns compares two exported summaries. Both must carry averages,
+// i.e. come from a run with at least one metrics window.
+func CompareRuns(a, b RunMetadata) (RunComparison, error) {
+	if a.Reads == nil || a.Writes == nil || b.Reads == nil || b.Writes == nil {
+		return RunComparison{}, errors.New("both summaries need read and write averages (export them after at least one metrics window)")
+	}
+	return RunComparison{
+		Reads:      compareOperation(*a.Reads, *b.Reads),
+		Writes:     compareOperation(*a.Writes, *b.Writes),
+		ErrorRateA: a.Totals.ErrorRate,
+		ErrorRateB: b.Totals.ErrorRate,
+	}, nil
+}
+
+func compareOperation(a, b metrics.OperationStats) OperationComparison {
+	return OperationComparison{
+		A:            a,
+		B:            b,
+		QPSChangePct: changePct(a.QPS, b.QPS),
+		P50ChangePct: changePct(a.LatencyP50, b.LatencyP50),
+		P99ChangePct: changePct(a.LatencyP99, b.LatencyP99),
+	}
+}
+
+// changePct is how much b differs from a, in percent of a
+func changePct(a, b float64) float64 {
+	if a == 0 {
+		return 0
+	}
+	return (b - a) / a * 100
+}
+
+// HandleCompare compares two exported summaries side by side
+func (h *Handlers) HandleCompare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CompareRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	comparison, err := CompareRuns(req.A, req.B)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, r, comparison)
+}
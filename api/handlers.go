@@ -2,31 +2,114 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
+	"supafirehose/db"
 	"supafirehose/load"
 	"supafirehose/metrics"
 )
 
 // Handlers holds the HTTP handler dependencies
 type Handlers struct {
-	controller *load.Controller
-	collector  *metrics.Collector
+	controller   *load.Controller
+	collector    *metrics.Collector
+	connMgr      *db.ConnectionManager
+	serverLimits *db.ServerLimits // nil if the startup probe failed
+	workloads    *load.Workloads  // Named workloads, including the default controller
+	limits       ConfigLimits
+	allowDBTest  bool          // POST /api/db/test may dial caller-supplied URLs
+	hub          *WebSocketHub // nil until SetMetricsHub
+
+	// pg_stat_statements counters captured when the run started
+	mu                 sync.Mutex
+	statementsBaseline map[string]db.StatementStat
 }
 
 // NewHandlers creates a new handlers instance
-func NewHandlers(controller *load.Controller, collector *metrics.Collector) *Handlers {
+func NewHandlers(controller *load.Controller, collector *metrics.Collector, connMgr *db.ConnectionManager, serverLimits *db.ServerLimits, workloads *load.Workloads) *Handlers {
 	return &Handlers{
-		controller: controller,
-		collector:  collector,
+		controller:   controller,
+		collector:    collector,
+		connMgr:      connMgr,
+		serverLimits: serverLimits,
+		workloads:    workloads,
 	}
 }
 
+// SetAllowDBTest enables POST /api/db/test, which is refused by default since
+// it connects to whatever host the caller names
+func (h *Handlers) SetAllowDBTest(allow bool) {
+	h.allowDBTest = allow
+}
+
+// ConfigLimits caps what POST /api/config accepts (0 = no cap)
+type ConfigLimits struct {
+	MaxConnections int `json:"max_connections"`
+	MaxReadQPS     int `json:"max_read_qps"`
+	MaxWriteQPS    int `json:"max_write_qps"`
+}
+
+// SetConfigLimits sets the maxima enforced on config updates
+func (h *Handlers) SetConfigLimits(limits ConfigLimits) {
+	h.limits = limits
+}
+
+// check returns an error naming every field of cfg over its limit, judging
+// rates by their effective values so total_qps is capped too. Connections
+// are those the database would see, including idle ones and the others
+// already configured by other workloads, which share the same budget.
+func (l ConfigLimits) check(cfg load.Config, others int) error {
+	readQPS, writeQPS := cfg.EffectiveQPS()
+	connections := cfg.DBConnections() + others
+	var over []string
+	if l.MaxConnections > 0 && connections > l.MaxConnections {
+		over = append(over, fmt.Sprintf("database connections %d (including idle and other workloads' %d) exceeds max %d", connections, others, l.MaxConnections))
+	}
+	if l.MaxReadQPS > 0 && readQPS > l.MaxReadQPS {
+		over = append(over, fmt.Sprintf("read qps %d exceeds max %d", readQPS, l.MaxReadQPS))
+	}
+	if l.MaxWriteQPS > 0 && writeQPS > l.MaxWriteQPS {
+		over = append(over, fmt.Sprintf("write qps %d exceeds max %d", writeQPS, l.MaxWriteQPS))
+	}
+	if len(over) > 0 {
+		return fmt.Errorf("config over limits: %s", strings.Join(over, "; "))
+	}
+	return nil
+}
+
 // StatusResponse is the response for GET /api/status
 type StatusResponse struct {
 	Running       bool        `json:"running"`
 	Config        load.Config `json:"config"`
 	UptimeSeconds float64     `json:"uptime_seconds"`
+	ReadsPaused   bool        `json:"reads_paused"`
+	WritesPaused  bool        `json:"writes_paused"`
+
+	// Recently inserted IDs cached for reads across all workloads
+	CachedIDs     int   `json:"cached_ids"`
+	CachedIDBytes int64 `json:"cached_id_bytes"`
+
+	// Maxima enforced on config updates, for bounding UI controls
+	Limits ConfigLimits `json:"limits"`
+
+	// How often metrics are broadcast (see POST /api/metrics-interval)
+	MetricsIntervalMs int64 `json:"metrics_interval_ms,omitempty"`
+
+	// Most recent failed connection attempt (omitted if none has failed)
+	LastConnectError *db.ConnectFailure `json:"last_connect_error,omitempty"`
+
+	// Measured throughput against the configured targets (omitted until the
+	// first metrics window)
+	Throughput *ThroughputStatus `json:"throughput,omitempty"`
+
+	// Server connection headroom probed at startup, not kept current
+	// (omitted if unknown)
+	*db.ServerLimits
 }
 
 // HandleStatus returns the current system status
@@ -36,27 +119,77 @@ func (h *Handlers) HandleStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	readsPaused, writesPaused := h.controller.Paused()
+	cachedIDs, cachedIDBytes := h.workloads.CachedIDs()
 	resp := StatusResponse{
 		Running:       h.controller.IsRunning(),
 		Config:        h.controller.GetConfig(),
 		UptimeSeconds: h.collector.Uptime().Seconds(),
+		ReadsPaused:   readsPaused,
+		WritesPaused:  writesPaused,
+		CachedIDs:     cachedIDs,
+		CachedIDBytes: cachedIDBytes,
+		Limits:        h.limits,
+		ServerLimits:  h.serverLimits,
+
+		LastConnectError: h.connMgr.LastConnectError(),
+	}
+	if h.hub != nil {
+		resp.MetricsIntervalMs = h.hub.Interval().Milliseconds()
+	}
+	if latest := h.collector.Latest(); latest != nil {
+		resp.Throughput = h.throughput(resp, latest)
 	}
 
-	writeJSON(w, resp)
+	writeJSON(w, r, resp)
 }
 
-// ConfigRequest is the request body for POST /api/config
-type ConfigRequest struct {
-	Connections int `json:"connections"`
-	ReadQPS     int `json:"read_qps"`
-	WriteQPS    int `json:"write_qps"`
-	ChurnRate   int `json:"churn_rate"`
+// saturatedFraction is how far below its target measured QPS must fall for
+// the database, rather than the rate limiter, to be the bottleneck
+const saturatedFraction = 0.9
+
+// ThroughputStatus compares the QPS measured in the latest metrics window
+// with the configured targets
+type ThroughputStatus struct {
+	ReadQPS        float64 `json:"read_qps"`
+	WriteQPS       float64 `json:"write_qps"`
+	TargetReadQPS  int     `json:"target_read_qps"`
+	TargetWriteQPS int     `json:"target_write_qps"`
+
+	// Measured reads or writes are under 90% of their target while running,
+	// unpaused, and past any ramp or warmup: the database can't keep up
+	Saturated bool `json:"saturated"`
 }
 
+// throughput builds the throughput status for resp from the latest snapshot
+func (h *Handlers) throughput(resp StatusResponse, latest *metrics.MetricsSnapshot) *ThroughputStatus {
+	t := &ThroughputStatus{
+		ReadQPS:  latest.Reads.QPS,
+		WriteQPS: latest.Writes.QPS,
+	}
+	t.TargetReadQPS, t.TargetWriteQPS = resp.Config.EffectiveQPS()
+
+	if !resp.Running || latest.WarmingUp || h.controller.Ramping() {
+		return t
+	}
+	below := func(measured float64, target int) bool {
+		return target > 0 && measured < saturatedFraction*float64(target)
+	}
+	t.Saturated = (!resp.ReadsPaused && below(t.ReadQPS, t.TargetReadQPS)) ||
+		(!resp.WritesPaused && below(t.WriteQPS, t.TargetWriteQPS))
+	return t
+}
+
+// ConfigRequest is the request body for POST /api/config. It mirrors
+// load.Config and replaces the whole configuration; fields omitted from the
+// body take their startup defaults.
+type ConfigRequest = load.Config
+
 // ConfigResponse is the response for POST /api/config
 type ConfigResponse struct {
-	OK     bool        `json:"ok"`
-	Config load.Config `json:"config"`
+	OK      bool        `json:"ok"`
+	Config  load.Config `json:"config"`
+	Warning string      `json:"warning,omitempty"`
 }
 
 // HandleConfig updates the workload configuration
@@ -66,27 +199,68 @@ func (h *Handlers) HandleConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req ConfigRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	cfg := h.controller.InitialConfig()
+	if err := decodeConfig(r, &cfg); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	cfg := load.Config{
-		Connections: req.Connections,
-		ReadQPS:     req.ReadQPS,
-		WriteQPS:    req.WriteQPS,
-		ChurnRate:   req.ChurnRate,
+	if err := h.controller.Check(cfg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.limits.check(cfg, h.workloads.DBConnections(load.DefaultWorkload)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	h.controller.UpdateConfig(cfg)
 
-	resp := ConfigResponse{
-		OK:     true,
-		Config: h.controller.GetConfig(),
+	writeJSON(w, r, ConfigResponse{
+		OK:      true,
+		Config:  h.controller.GetConfig(),
+		Warning: h.connectionWarning(cfg),
+	})
+}
+
+// connectionWarning warns when cfg's connections exceed the server's headroom
+func (h *Handlers) connectionWarning(cfg load.Config) string {
+	requested := cfg.DBConnections()
+	if h.serverLimits == nil || requested <= h.serverLimits.AvailableConnections {
+		return ""
 	}
+	return fmt.Sprintf("requested %d connections but server had only %d available at startup (max_connections=%d)",
+		requested, h.serverLimits.AvailableConnections, h.serverLimits.MaxConnections)
+}
 
-	writeJSON(w, resp)
+// decodeConfig applies the request body on top of cfg. Setting total_qps
+// without read_qps/write_qps switches to ratio mode by clearing the explicit
+// rates; setting either explicit rate switches back by clearing total_qps.
+func decodeConfig(r *http.Request, cfg *load.Config) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, cfg); err != nil {
+		return err
+	}
+
+	var set struct {
+		ReadQPS  *int `json:"read_qps"`
+		WriteQPS *int `json:"write_qps"`
+		TotalQPS *int `json:"total_qps"`
+	}
+	if err := json.Unmarshal(body, &set); err != nil {
+		return err
+	}
+	explicit := set.ReadQPS != nil || set.WriteQPS != nil
+	switch {
+	case set.TotalQPS != nil && !explicit:
+		cfg.ReadQPS, cfg.WriteQPS = 0, 0
+	case explicit && set.TotalQPS == nil:
+		cfg.TotalQPS = 0
+	}
+	return nil
 }
 
 // MessageResponse is a generic response with a message
@@ -102,14 +276,18 @@ func (h *Handlers) HandleStart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.controller.Start()
+	h.captureStatementsBaseline(r.Context())
+	if err := h.controller.Start(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	resp := MessageResponse{
 		OK:      true,
 		Message: "Load generator started",
 	}
 
-	writeJSON(w, resp)
+	writeJSON(w, r, resp)
 }
 
 // HandleStop stops the load generator
@@ -126,7 +304,74 @@ func (h *Handlers) HandleStop(w http.ResponseWriter, r *http.Request) {
 		Message: "Load generator stopped",
 	}
 
-	writeJSON(w, resp)
+	writeJSON(w, r, resp)
+}
+
+// HandlePause pauses one operation type (?type=reads or ?type=writes), or
+// all queries when type is omitted, while keeping connections open
+func (h *Handlers) HandlePause(w http.ResponseWriter, r *http.Request) {
+	h.handleSetPaused(w, r, true)
+}
+
+// HandleResume resumes an operation type paused via HandlePause
+func (h *Handlers) HandleResume(w http.ResponseWriter, r *http.Request) {
+	h.handleSetPaused(w, r, false)
+}
+
+func (h *Handlers) handleSetPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	opType := r.URL.Query().Get("type")
+	switch opType {
+	case "":
+		opType = "queries"
+		h.controller.SetPaused(true, true, paused)
+	case "reads":
+		h.controller.SetPaused(true, false, paused)
+	case "writes":
+		h.controller.SetPaused(false, true, paused)
+	default:
+		http.Error(w, "type must be reads, writes, or omitted for both", http.StatusBadRequest)
+		return
+	}
+
+	action := "resumed"
+	if paused {
+		action = "paused"
+	}
+	resp := MessageResponse{
+		OK:      true,
+		Message: fmt.Sprintf("%s %s", strings.ToUpper(opType[:1])+opType[1:], action),
+	}
+
+	writeJSON(w, r, resp)
+}
+
+// defaultStalledAfter is how long a query may run before its worker counts as stalled
+const defaultStalledAfter = 10 * time.Second
+
+// HandleWorkerHealth reports live, busy, and stalled workers
+// (GET /api/workers/health?stalled_after=10s)
+func (h *Handlers) HandleWorkerHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stalledAfter := defaultStalledAfter
+	if s := r.URL.Query().Get("stalled_after"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			http.Error(w, "stalled_after must be a positive duration", http.StatusBadRequest)
+			return
+		}
+		stalledAfter = d
+	}
+
+	writeJSON(w, r, h.controller.WorkerHealth(stalledAfter))
 }
 
 // HandleReset resets all metrics
@@ -143,10 +388,67 @@ func (h *Handlers) HandleReset(w http.ResponseWriter, r *http.Request) {
 		Message: "Metrics reset",
 	}
 
-	writeJSON(w, resp)
+	writeJSON(w, r, resp)
 }
 
-func writeJSON(w http.ResponseWriter, data interface{}) {
+// writeJSON encodes data as the response body. Callers may pass ?pretty=1
+// to indent the output and ?fields=a,b to keep only those top-level fields
+// (of each element, for array responses).
+func writeJSON(w http.ResponseWriter, r *http.Request, data interface{}) {
+	if fields := r.URL.Query().Get("fields"); fields != "" {
+		projected, err := projectFields(data, strings.Split(fields, ","))
+		if err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			return
+		}
+		data = projected
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(data)
+	enc := json.NewEncoder(w)
+	if pretty := r.URL.Query().Get("pretty"); pretty != "" && pretty != "0" && pretty != "false" {
+		enc.SetIndent("", "  ")
+	}
+	enc.Encode(data)
+}
+
+// projectFields round-trips data through JSON and keeps only the named
+// top-level keys, of each element when data is an array. Unknown field names
+// are ignored, as are fields on responses that aren't objects.
+func projectFields(data interface{}, fields []string) (json.RawMessage, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var elems []json.RawMessage
+	if err := json.Unmarshal(raw, &elems); err == nil {
+		for i, elem := range elems {
+			elems[i] = projectObject(elem, fields)
+		}
+		return json.Marshal(elems)
+	}
+	return projectObject(raw, fields), nil
+}
+
+// projectObject keeps only the named keys of the JSON object raw, returning
+// raw unchanged if it isn't an object
+func projectObject(raw json.RawMessage, fields []string) json.RawMessage {
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &all); err != nil || all == nil {
+		return raw
+	}
+
+	projected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if value, ok := all[field]; ok {
+			projected[field] = value
+		}
+	}
+	out, err := json.Marshal(projected)
+	if err != nil {
+		return raw
+	}
+	return out
 }
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Bounds on the broadcast interval accepted by POST /api/metrics-interval
+const (
+	minMetricsInterval = 10 * time.Millisecond
+	maxMetricsInterval = time.Minute
+)
+
+// MetricsIntervalRequest is the request body for POST /api/metrics-interval
+type MetricsIntervalRequest struct {
+	IntervalMs int64 `json:"interval_ms"`
+}
+
+// MetricsIntervalResponse is the response for POST /api/metrics-interval
+type MetricsIntervalResponse struct {
+	OK         bool  `json:"ok"`
+	IntervalMs int64 `json:"interval_ms"`
+}
+
+// SetMetricsHub sets the hub whose broadcast interval /api/metrics-interval
+// changes and /api/status reports
+func (h *Handlers) SetMetricsHub(hub *WebSocketHub) {
+	h.hub = hub
+}
+
+// HandleMetricsInterval changes how often metrics snapshots are taken and
+// broadcast, e.g. for finer resolution during part of a long run
+func (h *Handlers) HandleMetricsInterval(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.hub == nil {
+		http.Error(w, "Metrics broadcast is not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req MetricsIntervalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	interval := time.Duration(req.IntervalMs) * time.Millisecond
+	if interval < minMetricsInterval || interval > maxMetricsInterval {
+		http.Error(w, fmt.Sprintf("interval_ms must be between %d and %d",
+			minMetricsInterval.Milliseconds(), maxMetricsInterval.Milliseconds()), http.StatusBadRequest)
+		return
+	}
+
+	h.hub.SetInterval(interval)
+
+	writeJSON(w, r, MetricsIntervalResponse{OK: true, IntervalMs: interval.Milliseconds()})
+}
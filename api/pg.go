@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"supafirehose/db"
+)
+
+// dbTestTimeout bounds POST /api/db/test and POST /api/validate
+const dbTestTimeout = 10 * time.Second
+
+// DBTestRequest is the request body for POST /api/db/test
+type DBTestRequest struct {
+	URL string `json:"url"`
+}
+
+// HandleDBTest tries a connection string without switching the active one
+func (h *Handlers) HandleDBTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.allowDBTest {
+		http.Error(w, "Connection tests are disabled; set ALLOW_DB_TEST=true to enable them", http.StatusForbidden)
+		return
+	}
+
+	var req DBTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.URL == "" {
+		http.Error(w, "Request body must be {\"url\": \"postgres://...\"}", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTestTimeout)
+	defer cancel()
+
+	writeJSON(w, r, db.CheckConnString(ctx, req.URL))
+}
+
+// HandleValidate runs each workload query once against the database, rolling
+// back writes, so missing tables or permissions show up before a run. It
+// does not touch running workers.
+func (h *Handlers) HandleValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), dbTestTimeout)
+	defer cancel()
+
+	writeJSON(w, r, h.controller.DryRun(ctx))
+}
+
+// StatementsResponse is the response for GET /api/pg/statements
+type StatementsResponse struct {
+	// SinceStart is true when counters are deltas from when the run started
+	SinceStart bool               `json:"since_start"`
+	Statements []db.StatementStat `json:"statements"`
+}
+
+// HandleStatements returns pg_stat_statements entries for the workload's queries
+func (h *Handlers) HandleStatements(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 10
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	stats, err := h.connMgr.StatementStats(r.Context(), h.controller.Queries().All())
+	if errors.Is(err, db.ErrStatementsUnavailable) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	h.mu.Lock()
+	baseline := h.statementsBaseline
+	h.mu.Unlock()
+
+	// Subtract the counters seen at run start so results reflect this run only
+	resp := StatementsResponse{SinceStart: baseline != nil, Statements: make([]db.StatementStat, 0, len(stats))}
+	for _, s := range stats {
+		if base, ok := baseline[s.Query]; ok {
+			s.Calls -= base.Calls
+			s.Rows -= base.Rows
+			s.TotalTimeMs -= base.TotalTimeMs
+			s.MeanTimeMs = 0
+			if s.Calls > 0 {
+				s.MeanTimeMs = s.TotalTimeMs / float64(s.Calls)
+			}
+		}
+		if s.Calls > 0 {
+			resp.Statements = append(resp.Statements, s)
+		}
+	}
+
+	sort.Slice(resp.Statements, func(i, j int) bool {
+		return resp.Statements[i].TotalTimeMs > resp.Statements[j].TotalTimeMs
+	})
+	if len(resp.Statements) > limit {
+		resp.Statements = resp.Statements[:limit]
+	}
+
+	writeJSON(w, r, resp)
+}
+
+// captureStatementsBaseline snapshots pg_stat_statements so later reads can
+// report deltas attributable to the run. Failures are logged and ignored.
+func (h *Handlers) captureStatementsBaseline(ctx context.Context) {
+	if h.controller.IsRunning() {
+		return
+	}
+
+	stats, err := h.connMgr.StatementStats(ctx, h.controller.Queries().All())
+	if err != nil {
+		if !errors.Is(err, db.ErrStatementsUnavailable) {
+			slog.Warn("Could not snapshot pg_stat_statements", "error", err)
+		}
+		return
+	}
+
+	baseline := make(map[string]db.StatementStat, len(stats))
+	for _, s := range stats {
+		baseline[s.Query] = s
+	}
+
+	h.mu.Lock()
+	h.statementsBaseline = baseline
+	h.mu.Unlock()
+}
@@ -1,28 +1,57 @@
 package api
 
 import (
+	"encoding/json"
+	"html"
 	"io/fs"
+	"log/slog"
 	"net/http"
 	"strings"
 )
 
-// NewRouter creates the HTTP router with all routes configured
-func NewRouter(handlers *Handlers, wsHub *WebSocketHub, staticFS fs.FS) http.Handler {
+// NewRouter creates the HTTP router with all routes configured. basePath is
+// the prefix the app is served under (see WithBasePath); the frontend's
+// index.html is rewritten so its relative asset URLs and its API and
+// WebSocket URLs resolve under it.
+func NewRouter(handlers *Handlers, wsHub *WebSocketHub, staticFS fs.FS, basePath string) http.Handler {
 	mux := http.NewServeMux()
 
 	// API routes
 	mux.HandleFunc("/api/status", handlers.HandleStatus)
 	mux.HandleFunc("/api/config", handlers.HandleConfig)
+	mux.HandleFunc("/api/config/export", handlers.HandleConfigExport)
+	mux.HandleFunc("/api/config/import", handlers.HandleConfigImport)
 	mux.HandleFunc("/api/start", handlers.HandleStart)
 	mux.HandleFunc("/api/stop", handlers.HandleStop)
 	mux.HandleFunc("/api/reset", handlers.HandleReset)
+	mux.HandleFunc("/api/pause", handlers.HandlePause)
+	mux.HandleFunc("/api/resume", handlers.HandleResume)
+	mux.HandleFunc("/api/summary", handlers.HandleSummary)
+	mux.HandleFunc("/api/compare", handlers.HandleCompare)
+	mux.HandleFunc("/api/history", handlers.HandleHistory)
+	mux.HandleFunc("/api/history.csv", handlers.HandleHistoryCSV)
+	mux.HandleFunc("/api/histogram", handlers.HandleHistogram)
+	mux.HandleFunc("/api/metrics-interval", handlers.HandleMetricsInterval)
+	mux.HandleFunc("/api/pg/statements", handlers.HandleStatements)
+	mux.HandleFunc("/api/db/test", handlers.HandleDBTest)
+	mux.HandleFunc("/api/validate", handlers.HandleValidate)
+	mux.HandleFunc("/api/workers/health", handlers.HandleWorkerHealth)
+	mux.HandleFunc("/api/workloads", handlers.HandleWorkloads)
+	mux.HandleFunc("/api/workloads/{name}", handlers.HandleWorkload)
+	mux.HandleFunc("/api/workloads/{name}/{action}", handlers.HandleWorkloadAction)
 
-	// WebSocket route
+	// WebSocket routes
 	mux.HandleFunc("/ws/metrics", wsHub.HandleWebSocket)
+	mux.HandleFunc("/ws/errors", wsHub.HandleErrorStream)
+
+	// Unknown API/WebSocket paths get a JSON 404 rather than the SPA fallback
+	mux.HandleFunc("/api/", handleNotFound)
+	mux.HandleFunc("/ws/", handleNotFound)
 
 	// Static files with SPA fallback
 	if staticFS != nil {
 		fileServer := http.FileServer(http.FS(staticFS))
+		index := rewriteIndex(staticFS, basePath)
 		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 			// Try to serve the file directly
 			path := r.URL.Path
@@ -32,8 +61,16 @@ func NewRouter(handlers *Handlers, wsHub *WebSocketHub, staticFS fs.FS) http.Han
 
 			// Check if file exists
 			f, err := staticFS.Open(strings.TrimPrefix(path, "/"))
-			if err != nil {
-				// File not found, serve index.html for SPA routing
+			if err != nil || path == "/index.html" {
+				if f != nil {
+					f.Close()
+				}
+				// Serve index.html (also for SPA routing when not found)
+				if index != nil {
+					w.Header().Set("Content-Type", "text/html; charset=utf-8")
+					w.Write(index)
+					return
+				}
 				r.URL.Path = "/"
 				fileServer.ServeHTTP(w, r)
 				return
@@ -46,3 +83,54 @@ func NewRouter(handlers *Handlers, wsHub *WebSocketHub, staticFS fs.FS) http.Han
 
 	return mux
 }
+
+// WithBasePath serves h under basePath (e.g. "/supafirehose") for reverse
+// proxies that mount the app on a subpath, stripping the prefix before
+// routing. Requests outside the prefix get a 404. An empty basePath returns h.
+func WithBasePath(h http.Handler, basePath string) http.Handler {
+	if basePath == "" {
+		return h
+	}
+	mux := http.NewServeMux()
+	mux.Handle(basePath+"/", http.StripPrefix(basePath, h))
+	mux.Handle(basePath, http.RedirectHandler(basePath+"/", http.StatusMovedPermanently))
+	return mux
+}
+
+// rewriteIndex returns index.html with a <base href> of basePath, which the
+// frontend's relative asset URLs (built with Vite's base "./") resolve
+// against even when index.html is served for a deeper path, and with
+// window.__BASE_PATH__ set for its API and WebSocket URLs. It returns nil
+// when there is no base path (serve the file as-is).
+func rewriteIndex(staticFS fs.FS, basePath string) []byte {
+	if basePath == "" {
+		return nil
+	}
+	index, err := fs.ReadFile(staticFS, "index.html")
+	if err != nil {
+		slog.Warn("Could not read index.html for BASE_PATH", "error", err)
+		return nil
+	}
+	quoted, _ := json.Marshal(basePath)
+
+	// The base must come before any element with a URL to apply to it
+	head := `<head><base href="` + html.EscapeString(basePath+"/") + `">` +
+		"<script>window.__BASE_PATH__ = " + string(quoted) + ";</script>"
+	return []byte(strings.Replace(string(index), "<head>", head, 1))
+}
+
+// ErrorResponse is the body returned for unknown API routes
+type ErrorResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+// handleNotFound returns a JSON 404 for paths no API route matched
+func handleNotFound(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		OK:    false,
+		Error: "no route for " + r.URL.Path,
+	})
+}
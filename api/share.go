@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"supafirehose/load"
+)
+
+// SharedConfig is a complete config in a form that can be passed around:
+// the JSON itself plus a compact base64url encoding for URL fragments
+type SharedConfig struct {
+	Config  *load.Config `json:"config,omitempty"`
+	Encoded string       `json:"encoded,omitempty"`
+}
+
+// HandleConfigExport returns the full current config for sharing
+func (h *Handlers) HandleConfigExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	cfg := h.controller.GetConfig()
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, r, SharedConfig{
+		Config:  &cfg,
+		Encoded: base64.RawURLEncoding.EncodeToString(raw),
+	})
+}
+
+// HandleConfigImport replaces the current config with a shared one, given
+// either as "config" or as "encoded". Unlike POST /api/config nothing is
+// merged: fields missing from the shared config take their zero value.
+func (h *Handlers) HandleConfigImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var shared SharedConfig
+	if err := json.NewDecoder(r.Body).Decode(&shared); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var cfg load.Config
+	switch {
+	case shared.Config != nil:
+		cfg = *shared.Config
+	case shared.Encoded != "":
+		raw, err := base64.RawURLEncoding.DecodeString(shared.Encoded)
+		if err != nil || json.Unmarshal(raw, &cfg) != nil {
+			http.Error(w, "Invalid encoded config", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Request body must contain \"config\" or \"encoded\"", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.controller.Check(cfg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.limits.check(cfg, h.workloads.DBConnections(load.DefaultWorkload)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.controller.UpdateConfig(cfg)
+
+	writeJSON(w, r, ConfigResponse{
+		OK:      true,
+		Config:  h.controller.GetConfig(),
+		Warning: h.connectionWarning(cfg),
+	})
+}
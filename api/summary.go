@@ -0,0 +1,193 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"supafirehose/load"
+	"supafirehose/metrics"
+)
+
+// RunMetadata describes a run well enough to reproduce its results later
+type RunMetadata struct {
+	Config        load.Config        `json:"config"`
+	Table         string             `json:"table"`
+	ReadQuery     string             `json:"read_query"`
+	WriteQuery    string             `json:"write_query"`
+	ServerVersion string             `json:"server_version,omitempty"`
+	StartedAt     *time.Time         `json:"started_at,omitempty"`
+	EndedAt       *time.Time         `json:"ended_at,omitempty"` // omitted while running
+	Running       bool               `json:"running"`
+	Totals        metrics.TotalStats `json:"totals"`
+
+	// Read and write stats averaged over the retained metrics windows
+	// (omitted before the first window), for comparing runs
+	Reads  *metrics.OperationStats `json:"reads,omitempty"`
+	Writes *metrics.OperationStats `json:"writes,omitempty"`
+}
+
+// HandleSummary returns metadata and totals for the current or last run
+func (h *Handlers) HandleSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, r, h.runMetadata(r))
+}
+
+// HandleHistory returns the retained metrics snapshots, oldest first, so a
+// chart can be drawn on page load or a run exported afterwards
+func (h *Handlers) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, r, h.collector.History())
+}
+
+// HistogramResponse is the response for GET /api/histogram
+type HistogramResponse struct {
+	Reads  []metrics.Bucket `json:"reads"`
+	Writes []metrics.Bucket `json:"writes"`
+}
+
+// HandleHistogram returns the read and write latency bucket counts of the
+// last completed metrics window, to show bimodal or long-tailed
+// latency that P50/P99 hide
+func (h *Handlers) HandleHistogram(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reads, writes := h.collector.LatencyBuckets()
+	writeJSON(w, r, HistogramResponse{Reads: reads, Writes: writes})
+}
+
+// historyCSVHeader names the columns written by HandleHistoryCSV
+var historyCSVHeader = []string{
+	"timestamp", "read_qps", "write_qps",
+	"read_p50_ms", "read_p99_ms", "write_p50_ms", "write_p99_ms",
+	"errors", "active_connections",
+}
+
+// HandleHistoryCSV returns the retained metrics snapshots as CSV, one row
+// per snapshot, for loading a run into a spreadsheet. Rows are written as
+// they are formatted rather than building the whole file first.
+func (h *Handlers) HandleHistoryCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	history := h.collector.History()
+	filename := fmt.Sprintf("supafirehose-%s.csv", time.Now().UTC().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	cw := csv.NewWriter(w)
+	cw.Write(historyCSVHeader)
+	for _, s := range history {
+		cw.Write([]string{
+			time.UnixMilli(s.Timestamp).UTC().Format(time.RFC3339Nano),
+			formatCSVFloat(s.Reads.QPS),
+			formatCSVFloat(s.Writes.QPS),
+			formatCSVFloat(s.Reads.LatencyP50),
+			formatCSVFloat(s.Reads.LatencyP99),
+			formatCSVFloat(s.Writes.LatencyP50),
+			formatCSVFloat(s.Writes.LatencyP99),
+			strconv.FormatInt(s.Reads.Errors+s.Writes.Errors, 10),
+			strconv.Itoa(int(s.Pool.ActiveConnections)),
+		})
+	}
+	// Write errors mean the client went away; there is no one left to tell
+	cw.Flush()
+}
+
+// averageStats averages the read and write QPS and latencies of the windows
+// in history, skipping warmup windows, and takes the highest max latency.
+// Both are nil if no window counts.
+func averageStats(history []metrics.MetricsSnapshot) (reads, writes *metrics.OperationStats) {
+	var sumReads, sumWrites metrics.OperationStats
+	n := 0
+	for _, s := range history {
+		if s.WarmingUp {
+			continue
+		}
+		addStats(&sumReads, s.Reads)
+		addStats(&sumWrites, s.Writes)
+		n++
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	return divideStats(sumReads, n), divideStats(sumWrites, n)
+}
+
+// addStats adds s's rates and latencies to sum, keeping the highest maxes
+func addStats(sum *metrics.OperationStats, s metrics.OperationStats) {
+	sum.QPS += s.QPS
+	sum.LatencyP50 += s.LatencyP50
+	sum.LatencyP90 += s.LatencyP90
+	sum.LatencyP95 += s.LatencyP95
+	sum.LatencyP99 += s.LatencyP99
+	sum.LatencyAvg += s.LatencyAvg
+	sum.LatencyMax = max(sum.LatencyMax, s.LatencyMax)
+	sum.LatencyMaxCumulative = max(sum.LatencyMaxCumulative, s.LatencyMaxCumulative)
+	sum.Errors += s.Errors
+	sum.Retries += s.Retries
+}
+
+// divideStats turns a sum from addStats over n windows into an average;
+// errors and retries stay totals
+func divideStats(sum metrics.OperationStats, n int) *metrics.OperationStats {
+	f := float64(n)
+	sum.QPS /= f
+	sum.LatencyP50 /= f
+	sum.LatencyP90 /= f
+	sum.LatencyP95 /= f
+	sum.LatencyP99 /= f
+	sum.LatencyAvg /= f
+	return &sum
+}
+
+// formatCSVFloat formats v with 3 decimal places and no exponent
+func formatCSVFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', 3, 64)
+}
+
+// runMetadata assembles the RunMetadata from the controller, collector, and database
+func (h *Handlers) runMetadata(r *http.Request) RunMetadata {
+	queries := h.controller.Queries()
+	meta := RunMetadata{
+		Config:     h.controller.GetConfig(),
+		Table:      queries.Table,
+		ReadQuery:  queries.Read,
+		WriteQuery: queries.Write,
+		Running:    h.controller.IsRunning(),
+		Totals:     h.collector.Totals(),
+	}
+	meta.Reads, meta.Writes = averageStats(h.collector.History())
+
+	started, stopped := h.controller.RunTimes()
+	if !started.IsZero() {
+		meta.StartedAt = &started
+	}
+	if !stopped.IsZero() {
+		meta.EndedAt = &stopped
+	}
+
+	version, err := h.connMgr.ServerVersion(r.Context())
+	if err != nil {
+		slog.Warn("Could not read server version", "error", err)
+	}
+	meta.ServerVersion = version
+
+	return meta
+}
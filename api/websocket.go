@@ -2,11 +2,13 @@ package api
 
 import (
 	"encoding/json"
-	"log"
+	"log/slog"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"supafirehose/load"
 	"supafirehose/metrics"
 
 	"github.com/gorilla/websocket"
@@ -25,15 +27,40 @@ type WebSocketHub struct {
 	mu        sync.RWMutex
 	clients   map[*websocket.Conn]bool
 	collector *metrics.Collector
-	interval  time.Duration
+	exporter  metrics.Exporter // Receives every snapshot, even with no clients
+	workloads *load.Workloads  // Additional workloads rolled up into each snapshot
+
+	// Broadcast interval; changes are sent to the broadcast loop on
+	// intervalChanged so it can reset its ticker
+	interval        atomic.Int64
+	intervalChanged chan struct{}
 }
 
 // NewWebSocketHub creates a new WebSocket hub
-func NewWebSocketHub(collector *metrics.Collector, interval time.Duration) *WebSocketHub {
-	return &WebSocketHub{
-		clients:   make(map[*websocket.Conn]bool),
-		collector: collector,
-		interval:  interval,
+func NewWebSocketHub(collector *metrics.Collector, interval time.Duration, exporter metrics.Exporter, workloads *load.Workloads) *WebSocketHub {
+	hub := &WebSocketHub{
+		clients:         make(map[*websocket.Conn]bool),
+		collector:       collector,
+		exporter:        exporter,
+		workloads:       workloads,
+		intervalChanged: make(chan struct{}, 1),
+	}
+	hub.interval.Store(int64(interval))
+	return hub
+}
+
+// Interval returns how often metrics are broadcast
+func (hub *WebSocketHub) Interval() time.Duration {
+	return time.Duration(hub.interval.Load())
+}
+
+// SetInterval changes how often metrics are broadcast, starting a new
+// window now. Safe to call while StartBroadcast is running.
+func (hub *WebSocketHub) SetInterval(interval time.Duration) {
+	hub.interval.Store(int64(interval))
+	select {
+	case hub.intervalChanged <- struct{}{}:
+	default: // A change is already pending; the loop will read the latest value
 	}
 }
 
@@ -41,7 +68,7 @@ func NewWebSocketHub(collector *metrics.Collector, interval time.Duration) *WebS
 func (hub *WebSocketHub) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+		slog.Warn("WebSocket upgrade error", "error", err)
 		return
 	}
 
@@ -68,16 +95,93 @@ func (hub *WebSocketHub) HandleWebSocket(w http.ResponseWriter, r *http.Request)
 	}()
 }
 
+// HandleErrorStream streams every distinct error as it happens (deduplicated
+// per second) over a WebSocket, independent of the metrics cadence
+func (hub *WebSocketHub) HandleErrorStream(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Warn("WebSocket upgrade error", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	events, unsubscribe := hub.collector.SubscribeErrors()
+	defer unsubscribe()
+
+	// Read messages (mainly to detect disconnect)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case event := <-events:
+			conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		}
+	}
+}
+
 // StartBroadcast starts the metrics broadcast loop
 func (hub *WebSocketHub) StartBroadcast() {
-	ticker := time.NewTicker(hub.interval)
+	interval := hub.Interval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	var lastErrorsVersion int64
-	for range ticker.C {
+	workloadErrorsVersions := make(map[string]int64)
+	lastTick := time.Now()
+	changed := false
+	for {
+		select {
+		case <-hub.intervalChanged:
+			interval = hub.Interval()
+			ticker.Reset(interval)
+			changed = true
+			continue
+		case <-ticker.C:
+		}
+
+		// The window spanning an interval change is neither the old nor the
+		// new length, so rates use its actual duration
+		window := interval
+		now := time.Now()
+		if changed {
+			window = now.Sub(lastTick)
+			changed = false
+		}
+		lastTick = now
+
 		errVersion := hub.collector.ErrorsVersion()
-		snapshot := hub.collector.Snapshot(hub.interval, lastErrorsVersion)
+		snapshot := hub.collector.Snapshot(window, lastErrorsVersion)
 		lastErrorsVersion = errVersion
+
+		// Roll up each additional workload's metrics under its name. The
+		// versions are rebuilt from the current set, so removed workloads
+		// drop out.
+		collectors := hub.workloads.Collectors()
+		versions := make(map[string]int64, len(collectors))
+		for name, collector := range collectors {
+			if snapshot.Workloads == nil {
+				snapshot.Workloads = make(map[string]metrics.MetricsSnapshot)
+			}
+			version := collector.ErrorsVersion()
+			snapshot.Workloads[name] = collector.Snapshot(window, workloadErrorsVersions[name])
+			versions[name] = version
+		}
+		workloadErrorsVersions = versions
+
+		hub.exporter.Export(snapshot)
 		hub.broadcast(snapshot)
 	}
 }
@@ -85,7 +189,7 @@ func (hub *WebSocketHub) StartBroadcast() {
 func (hub *WebSocketHub) broadcast(snapshot metrics.MetricsSnapshot) {
 	data, err := json.Marshal(snapshot)
 	if err != nil {
-		log.Printf("Failed to marshal metrics: %v", err)
+		slog.Error("Failed to marshal metrics", "error", err)
 		return
 	}
 
@@ -96,7 +200,7 @@ func (hub *WebSocketHub) broadcast(snapshot metrics.MetricsSnapshot) {
 		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
 		err := conn.WriteMessage(websocket.TextMessage, data)
 		if err != nil {
-			log.Printf("WebSocket write error: %v", err)
+			slog.Warn("WebSocket write error", "error", err)
 			conn.Close()
 			go func(c *websocket.Conn) {
 				hub.mu.Lock()
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"net/http"
+
+	"supafirehose/load"
+	"supafirehose/metrics"
+)
+
+// WorkloadStatus describes one named workload
+type WorkloadStatus struct {
+	Name    string             `json:"name"`
+	Running bool               `json:"running"`
+	Config  load.Config        `json:"config"`
+	Totals  metrics.TotalStats `json:"totals"`
+}
+
+// HandleWorkloads lists all workloads (GET /api/workloads)
+func (h *Handlers) HandleWorkloads(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	statuses := []WorkloadStatus{}
+	for _, name := range h.workloads.Names() {
+		if c, ok := h.workloads.Get(name); ok {
+			statuses = append(statuses, workloadStatus(name, c))
+		}
+	}
+
+	writeJSON(w, r, statuses)
+}
+
+// HandleWorkload returns (GET) or removes (DELETE) /api/workloads/{name}
+func (h *Handlers) HandleWorkload(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	switch r.Method {
+	case http.MethodGet:
+		c, ok := h.workloads.Get(name)
+		if !ok {
+			http.Error(w, "Workload not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, r, workloadStatus(name, c))
+	case http.MethodDelete:
+		if err := h.workloads.Remove(name); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, r, MessageResponse{OK: true, Message: "Workload " + name + " removed"})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleWorkloadAction handles POST /api/workloads/{name}/{config,start,stop}.
+// Configuring an unknown workload creates it.
+func (h *Handlers) HandleWorkloadAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := r.PathValue("name")
+	action := r.PathValue("action")
+
+	if action == "config" {
+		// A new workload is only registered once its config is accepted
+		c, exists := h.workloads.Get(name)
+		if !exists {
+			var err error
+			if c, err = h.workloads.New(name); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		cfg := c.InitialConfig()
+		if err := decodeConfig(r, &cfg); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := c.Check(cfg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.limits.check(cfg, h.workloads.DBConnections(name)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !exists {
+			c = h.workloads.Add(name, c)
+		}
+		c.UpdateConfig(cfg)
+
+		writeJSON(w, r, ConfigResponse{OK: true, Config: c.GetConfig()})
+		return
+	}
+
+	c, ok := h.workloads.Get(name)
+	if !ok {
+		http.Error(w, "Workload not found", http.StatusNotFound)
+		return
+	}
+
+	switch action {
+	case "start":
+		if err := c.Start(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, r, MessageResponse{OK: true, Message: "Workload " + name + " started"})
+	case "stop":
+		c.Stop()
+		writeJSON(w, r, MessageResponse{OK: true, Message: "Workload " + name + " stopped"})
+	default:
+		handleNotFound(w, r)
+	}
+}
+
+func workloadStatus(name string, c *load.Controller) WorkloadStatus {
+	return WorkloadStatus{
+		Name:    name,
+		Running: c.IsRunning(),
+		Config:  c.GetConfig(),
+		Totals:  c.Collector().Totals(),
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -10,39 +11,262 @@ import (
 type Config struct {
 	// Database
 	DatabaseURL string
+	TablePrefix string
+	Tablespace  string
+
+	// Split reads and writes across servers (e.g. a replica and the primary);
+	// each falls back to DatabaseURL when empty
+	ReadDatabaseURL  string
+	WriteDatabaseURL string
 
 	// Server
 	HTTPPort int
+	BasePath string // Path prefix when reverse-proxied on a subpath ("" = root)
+
+	// Logging
+	LogFormat string // "text" or "json"
+	LogLevel  string // "debug", "info", "warn", or "error"
 
 	// Load defaults
 	DefaultConnections int
 	DefaultReadQPS     int
 	DefaultWriteQPS    int
+	IdleConnections    int // Connections held open with only a keepalive
 
 	// Limits
-	MaxConnections int
-	MaxReadQPS     int
-	MaxWriteQPS    int
+	MaxConnections       int
+	MaxReadQPS           int
+	MaxWriteQPS          int
+	ReconnectConcurrency int
+	TransientRetries     int // Reissues of a query whose connection broke
+	ConnLogSample        int // Log every nth connection failure (0 = only new reasons)
 
 	// Metrics
-	MetricsInterval time.Duration
-	MaxUserID       int64
+	MetricsInterval     time.Duration
+	QPSSmoothingAlpha   float64
+	MetricsHistorySize  int
+	RecentErrorsMax     int
+	ErrorSampleInterval time.Duration
+	TableStatsInterval  time.Duration
+	WALStatsInterval    time.Duration
+	MaxUserID           int64
+	ExplainSampleRate   float64
+	WarmupQueries       int
+	WarmupSeconds       int
+	RampSeconds         int
+
+	// Writes
+	WriteRetries             int
+	VisibilityCheckRate      float64
+	ErrorInjectionRate       float64
+	StatementsPerTransaction int
+	WriteBatchSize           int
+	WriteMode                string // "insert" or "upsert"
+	UpsertConflictRate       float64
+
+	// Per-connection mix, e.g. "read=70,insert=20,update=10" (empty = split
+	// connections into dedicated readers and writers)
+	OperationWeights string
+
+	// Chaos mode: periodically sever a fraction of connections at once
+	ChaosIntervalSec int
+	ChaosFraction    float64
+
+	// Per-operation in-flight caps (0 = unlimited)
+	MaxConcurrentReads  int
+	MaxConcurrentWrites int
+
+	// Per-query deadline (0 = none)
+	QueryTimeoutMs int
+
+	// How long Stop waits for in-flight queries (0 = cancel at once)
+	DrainTimeoutMs int
+
+	// Simulated network latency
+	InjectLatencyMs       int
+	InjectLatencyJitterMs int
+
+	// Fixed lifetime (± jitter percent) for persistent connections
+	MaxConnLifetimeSec       int
+	MaxConnLifetimeJitterPct int
+
+	// Open a new connection for every query
+	PerQueryConnect bool
+
+	// Borrow connections per query from a shared pgxpool
+	PoolMode bool
+
+	// Prepare queries once per connection and run them by name
+	PreparedStatements bool
+
+	// Read ID selection
+	ReadStrategy string
+	RecentWindow int
+
+	// Point lookups, range fetches of ReadRangeSize rows, or lookups of
+	// ReadInListSize IDs at once
+	ReadMode       string
+	ReadRangeSize  int
+	ReadInListSize int
+
+	// Two-tier topology: app instances each holding a small connection pool
+	AppInstances           int
+	ConnectionsPerInstance int
+
+	// Weighted read endpoints as "name[:weight]=url ..." (see ParseEndpoints)
+	ReadEndpoints string
+
+	// Target resource usage source (HTTP endpoint or shell command printing JSON)
+	TargetMetricsURL     string
+	TargetMetricsCommand string
+	TargetPollInterval   time.Duration
+
+	// Safety limit on total process runtime (0 = unlimited)
+	MaxRuntime     time.Duration
+	MaxRuntimeExit bool
+
+	// StatsD/DogStatsD export (disabled when StatsDAddr is empty)
+	StatsDAddr   string
+	StatsDPrefix string
+
+	// Storage experiments that ALTER the workload table; refused unless
+	// AllowDestructive is also set
+	DisableAutovacuum bool
+	AllowDestructive  bool
+
+	// Let POST /api/db/test dial arbitrary connection strings, which lets
+	// API callers probe any host the server can reach
+	AllowDBTest bool
 }
 
 // Load reads configuration from environment variables with defaults
 func Load() *Config {
 	return &Config{
 		DatabaseURL:        getEnv("DATABASE_URL", "postgres://localhost:5432/pooler_demo"),
+		ReadDatabaseURL:    getEnv("READ_DATABASE_URL", ""),
+		WriteDatabaseURL:   getEnv("WRITE_DATABASE_URL", ""),
+		TablePrefix:        getEnv("TABLE_PREFIX", ""),
+		Tablespace:         getEnv("TABLESPACE", ""),
 		HTTPPort:           getEnvInt("HTTP_PORT", 8080),
+		BasePath:           normalizeBasePath(getEnv("BASE_PATH", "")),
 		DefaultConnections: getEnvInt("DEFAULT_CONNECTIONS", 10),
 		DefaultReadQPS:     getEnvInt("DEFAULT_READ_QPS", 100),
 		DefaultWriteQPS:    getEnvInt("DEFAULT_WRITE_QPS", 10),
+		IdleConnections:    getEnvInt("IDLE_CONNECTIONS", 0),
 		MaxConnections:     getEnvInt("MAX_CONNECTIONS", 20000),
 		MaxReadQPS:         getEnvInt("MAX_READ_QPS", 500000),
 		MaxWriteQPS:        getEnvInt("MAX_WRITE_QPS", 500000),
-		MetricsInterval:    getEnvDuration("METRICS_INTERVAL", 100*time.Millisecond),
-		MaxUserID:          getEnvInt64("MAX_USER_ID", 100000),
+
+		ReconnectConcurrency: getEnvInt("RECONNECT_CONCURRENCY", 0),
+		ConnLogSample:        getEnvInt("CONN_LOG_SAMPLE", 100),
+
+		MetricsInterval:     getEnvDuration("METRICS_INTERVAL", 100*time.Millisecond),
+		QPSSmoothingAlpha:   getEnvFloat("QPS_SMOOTHING_ALPHA", 0.2),
+		MetricsHistorySize:  getEnvInt("METRICS_HISTORY_SIZE", 3000),
+		RecentErrorsMax:     getEnvInt("RECENT_ERRORS_MAX", 10),
+		ErrorSampleInterval: getEnvDuration("ERROR_SAMPLE_INTERVAL", 10*time.Second),
+		TableStatsInterval:  getEnvDuration("TABLE_STATS_INTERVAL", 5*time.Second),
+		WALStatsInterval:    getEnvDuration("WAL_STATS_INTERVAL", time.Second),
+		MaxUserID:           getEnvInt64("MAX_USER_ID", 100000),
+		ExplainSampleRate:   getEnvFloat("EXPLAIN_SAMPLE_RATE", 0),
+		WarmupQueries:       getEnvInt("WARMUP_QUERIES", 0),
+		WarmupSeconds:       getEnvInt("WARMUP_SECONDS", 0),
+		RampSeconds:         getEnvInt("RAMP_SECONDS", 0),
+		WriteRetries:        getEnvInt("WRITE_RETRIES", 0),
+		TransientRetries:    getEnvInt("TRANSIENT_RETRIES", 0),
+
+		VisibilityCheckRate:      getEnvFloat("VISIBILITY_CHECK_RATE", 0),
+		ErrorInjectionRate:       getEnvFloat("ERROR_INJECTION_RATE", 0),
+		StatementsPerTransaction: getEnvInt("STATEMENTS_PER_TRANSACTION", 0),
+		WriteBatchSize:           getEnvInt("WRITE_BATCH_SIZE", 0),
+		WriteMode:                getEnv("WRITE_MODE", "insert"),
+		UpsertConflictRate:       getEnvFloat("UPSERT_CONFLICT_RATE", 0.5),
+		OperationWeights:         getEnv("OPERATION_WEIGHTS", ""),
+
+		ChaosIntervalSec: getEnvInt("CHAOS_INTERVAL_SEC", 0),
+		ChaosFraction:    getEnvFloat("CHAOS_FRACTION", 0),
+
+		MaxConcurrentReads:  getEnvInt("MAX_CONCURRENT_READS", 0),
+		MaxConcurrentWrites: getEnvInt("MAX_CONCURRENT_WRITES", 0),
+
+		QueryTimeoutMs: getEnvInt("QUERY_TIMEOUT_MS", 0),
+		DrainTimeoutMs: getEnvInt("DRAIN_TIMEOUT_MS", 5000),
+
+		// Simulated network latency
+		InjectLatencyMs:       getEnvInt("INJECT_LATENCY_MS", 0),
+		InjectLatencyJitterMs: getEnvInt("INJECT_LATENCY_JITTER_MS", 0),
+
+		// Fixed connection lifetime
+		MaxConnLifetimeSec:       getEnvInt("MAX_CONN_LIFETIME_SEC", 0),
+		MaxConnLifetimeJitterPct: getEnvInt("MAX_CONN_LIFETIME_JITTER_PCT", 0),
+
+		PerQueryConnect: getEnvBool("PER_QUERY_CONNECT", false),
+		PoolMode:        getEnvBool("POOL_MODE", false),
+
+		PreparedStatements: getEnvBool("PREPARED_STATEMENTS", false),
+
+		// Read ID selection
+		ReadStrategy: getEnv("READ_STRATEGY", "uniform"),
+		RecentWindow: getEnvInt("RECENT_WINDOW", 1000),
+
+		// Read shape
+		ReadMode:       getEnv("READ_MODE", "point"),
+		ReadRangeSize:  getEnvInt("READ_RANGE_SIZE", 100),
+		ReadInListSize: getEnvInt("READ_IN_LIST_SIZE", 100),
+
+		// App instance pools
+		AppInstances:           getEnvInt("APP_INSTANCES", 0),
+		ConnectionsPerInstance: getEnvInt("CONNECTIONS_PER_INSTANCE", 5),
+
+		ReadEndpoints: getEnv("READ_ENDPOINTS", ""),
+
+		// Target resource usage
+		TargetMetricsURL:     getEnv("TARGET_METRICS_URL", ""),
+		TargetMetricsCommand: getEnv("TARGET_METRICS_COMMAND", ""),
+		TargetPollInterval:   getEnvDuration("TARGET_POLL_INTERVAL", time.Second),
+
+		// Safety limit on total runtime
+		MaxRuntime:     getEnvDuration("MAX_RUNTIME", 0),
+		MaxRuntimeExit: getEnvBool("MAX_RUNTIME_EXIT", false),
+
+		// StatsD export
+		StatsDAddr:   getEnv("STATSD_ADDR", ""),
+		StatsDPrefix: getEnv("STATSD_PREFIX", "supafirehose."),
+
+		// Storage experiments
+		DisableAutovacuum: getEnvBool("DISABLE_AUTOVACUUM", false),
+		AllowDestructive:  getEnvBool("ALLOW_DESTRUCTIVE", false),
+
+		AllowDBTest: getEnvBool("ALLOW_DB_TEST", false),
+
+		// Logging
+		LogFormat: getEnv("LOG_FORMAT", "text"),
+		LogLevel:  getEnv("LOG_LEVEL", "info"),
+	}
+}
+
+// UsersTable returns the name of the users table including any configured prefix
+func (c *Config) UsersTable() string {
+	return c.TablePrefix + "users"
+}
+
+// PrimaryURL returns the connection string for writes and everything else
+// that must reach the primary (stats and setup)
+func (c *Config) PrimaryURL() string {
+	if c.WriteDatabaseURL != "" {
+		return c.WriteDatabaseURL
 	}
+	return c.DatabaseURL
+}
+
+// normalizeBasePath turns "supafirehose/" or "/supafirehose/" into
+// "/supafirehose", and "/" into ""
+func normalizeBasePath(p string) string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return ""
+	}
+	return "/" + p
 }
 
 func getEnv(key, defaultValue string) string {
@@ -70,6 +294,24 @@ func getEnvInt64(key string, defaultValue int64) int64 {
 	return defaultValue
 }
 
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"supafirehose/load"
+)
+
+// ParseEndpoints parses a whitespace-separated list of name[:weight]=url
+// entries, e.g. "us-east:3=postgres://a/db eu-west=postgres://b/db".
+// Weight defaults to 1.
+func ParseEndpoints(s string) ([]load.Endpoint, error) {
+	var endpoints []load.Endpoint
+	seen := make(map[string]bool)
+	for _, entry := range strings.Fields(s) {
+		spec, url, ok := strings.Cut(entry, "=")
+		if !ok || url == "" {
+			return nil, fmt.Errorf("endpoint %q must be name[:weight]=url", entry)
+		}
+
+		name, weightStr, hasWeight := strings.Cut(spec, ":")
+		weight := 1
+		if hasWeight {
+			w, err := strconv.Atoi(weightStr)
+			if err != nil || w < 1 {
+				return nil, fmt.Errorf("endpoint %q has invalid weight %q", name, weightStr)
+			}
+			weight = w
+		}
+		if name == "" {
+			return nil, fmt.Errorf("endpoint is missing a name before '='")
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate endpoint name %q", name)
+		}
+		seen[name] = true
+
+		endpoints = append(endpoints, load.Endpoint{Name: name, URL: url, Weight: weight})
+	}
+	return endpoints, nil
+}
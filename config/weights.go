@@ -0,0 +1,37 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"supafirehose/load"
+)
+
+// ParseOperationWeights parses a comma-separated list of op=weight entries,
+// e.g. "read=70,insert=20,update=10". Omitted operations get weight 0.
+func ParseOperationWeights(s string) (load.OperationWeights, error) {
+	var w load.OperationWeights
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		op, weightStr, ok := strings.Cut(entry, "=")
+		weight, err := strconv.Atoi(strings.TrimSpace(weightStr))
+		if !ok || err != nil || weight < 0 {
+			return load.OperationWeights{}, fmt.Errorf("operation weight %q must be op=weight with a non-negative integer weight", entry)
+		}
+		switch strings.TrimSpace(op) {
+		case "read":
+			w.Read = weight
+		case "insert":
+			w.Insert = weight
+		case "update":
+			w.Update = weight
+		default:
+			return load.OperationWeights{}, fmt.Errorf("unknown operation %q (want read, insert, or update)", op)
+		}
+	}
+	return w, nil
+}
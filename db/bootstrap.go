@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Bootstrap creates table as init.sql does, with its unique email index
+// unless indexes is false, on tablespace ("" for the database default) if it
+// doesn't exist, and seeds it with rows users when empty so reads up to that
+// ID find a row. It returns how many rows were seeded (0 if the table
+// already had data).
+func (cm *ConnectionManager) Bootstrap(ctx context.Context, table, tablespace string, rows int64, indexes bool) (int64, error) {
+	conn, err := dial(ctx, cm.connString)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	quoted := pgx.Identifier{table}.Sanitize()
+	create := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id         BIGSERIAL PRIMARY KEY,
+			username   VARCHAR(255) NOT NULL,
+			email      VARCHAR(255) NOT NULL,
+			created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+		)`, quoted)
+	if tablespace != "" {
+		create += " TABLESPACE " + pgx.Identifier{tablespace}.Sanitize()
+	}
+	if _, err := conn.Exec(ctx, create); err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", table, err)
+	}
+
+	if indexes {
+		index := pgx.Identifier{table + "_email_key"}.Sanitize()
+		if _, err := conn.Exec(ctx, fmt.Sprintf("CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (email)", index, quoted)); err != nil {
+			return 0, fmt.Errorf("failed to index %s: %w", table, err)
+		}
+	}
+
+	var populated bool
+	if err := conn.QueryRow(ctx, fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s)", quoted)).Scan(&populated); err != nil {
+		return 0, fmt.Errorf("failed to check %s for rows: %w", table, err)
+	}
+	if populated || rows <= 0 {
+		return 0, nil
+	}
+
+	tag, err := conn.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s (username, email)
+		SELECT 'user_' || i, 'user_' || i || '@example.com'
+		FROM generate_series(1, $1::bigint) AS i
+		ON CONFLICT DO NOTHING`, quoted), rows)
+	if err != nil {
+		return 0, fmt.Errorf("failed to seed %s: %w", table, err)
+	}
+	if _, err := conn.Exec(ctx, "ANALYZE "+quoted); err != nil {
+		return 0, fmt.Errorf("failed to analyze %s: %w", table, err)
+	}
+	return tag.RowsAffected(), nil
+}
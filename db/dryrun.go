@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// QueryCheck is one workload query to try in a dry run
+type QueryCheck struct {
+	Name  string
+	Query string
+	Args  []any
+}
+
+// QueryCheckResult is the outcome of one QueryCheck
+type QueryCheckResult struct {
+	Name    string  `json:"name"`
+	OK      bool    `json:"ok"`
+	QueryMs float64 `json:"query_ms,omitempty"`
+	Error   string  `json:"error,omitempty"` // Password redacted
+}
+
+// DryRunResult is the outcome of DryRun
+type DryRunResult struct {
+	OK      bool               `json:"ok"`
+	Error   string             `json:"error,omitempty"` // Connection failure (password redacted)
+	Queries []QueryCheckResult `json:"queries,omitempty"`
+}
+
+// DryRun runs each check once on a dedicated connection to connString (""
+// for the primary), each in its own transaction that is rolled back so writes
+// leave no rows behind (sequences still advance). A read matching no row
+// passes. It does not count against the connection limit.
+func (cm *ConnectionManager) DryRun(ctx context.Context, connString string, checks []QueryCheck) DryRunResult {
+	result := DryRunResult{OK: true}
+	if connString == "" {
+		connString = cm.connString
+	}
+
+	conn, err := dial(ctx, connString)
+	if err != nil {
+		result.OK = false
+		result.Error = redactError(err, connString).Error()
+		return result
+	}
+	defer conn.Close(context.Background())
+
+	for _, check := range checks {
+		r := QueryCheckResult{Name: check.Name}
+		start := time.Now()
+		tx, err := conn.Begin(ctx)
+		if err == nil {
+			_, err = tx.Exec(ctx, check.Query, check.Args...)
+			tx.Rollback(ctx)
+		}
+		if err != nil {
+			r.Error = redactError(err, connString).Error()
+			result.OK = false
+		} else {
+			r.OK = true
+			r.QueryMs = float64(time.Since(start).Microseconds()) / 1000.0
+		}
+		result.Queries = append(result.Queries, r)
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// connLimiter is a resizable counting semaphore that caps concurrent connections
+type connLimiter struct {
+	mu      sync.Mutex
+	limit   int // 0 means unlimited
+	inUse   int
+	changed chan struct{} // closed and replaced when a slot frees or the limit changes
+
+	waiting atomic.Int32 // callers currently blocked in acquire
+	waitNs  atomic.Int64 // total wait since last takeWait
+	waits   atomic.Int64 // acquisitions since last takeWait
+}
+
+func newConnLimiter() *connLimiter {
+	return &connLimiter{changed: make(chan struct{})}
+}
+
+// acquire blocks until a slot is free or ctx is done
+func (l *connLimiter) acquire(ctx context.Context) error {
+	start := time.Now()
+	blocked := false
+	defer func() {
+		if blocked {
+			l.waiting.Add(-1)
+		}
+	}()
+
+	for {
+		l.mu.Lock()
+		if l.limit == 0 || l.inUse < l.limit {
+			l.inUse++
+			l.mu.Unlock()
+			l.waitNs.Add(int64(time.Since(start)))
+			l.waits.Add(1)
+			return nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		if !blocked {
+			blocked = true
+			l.waiting.Add(1)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// release frees a slot taken by acquire
+func (l *connLimiter) release() {
+	l.mu.Lock()
+	l.inUse--
+	l.broadcastLocked()
+	l.mu.Unlock()
+}
+
+// setLimit changes the number of slots (0 = unlimited)
+func (l *connLimiter) setLimit(n int) {
+	l.mu.Lock()
+	l.limit = max(n, 0)
+	l.broadcastLocked()
+	l.mu.Unlock()
+}
+
+func (l *connLimiter) broadcastLocked() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// takeWait returns the average acquire wait since the last call and resets it
+func (l *connLimiter) takeWait() time.Duration {
+	n := l.waits.Swap(0)
+	total := l.waitNs.Swap(0)
+	if n == 0 {
+		return 0
+	}
+	return time.Duration(total / n)
+}
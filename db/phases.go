@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ConnectPhases splits a successful connect into its sub-phases
+type ConnectPhases struct {
+	Total   time.Duration // The whole connect, not counting waits for a connection or dial slot
+	Dial    time.Duration // TCP (or unix socket) dial
+	TLS     time.Duration // SSLRequest and TLS handshake (0 without TLS)
+	Startup time.Duration // Startup message through ReadyForQuery, including auth
+}
+
+// SetConnectObserver registers fn to receive the phase breakdown of every
+// successful connect. Call before any Connect; it is not safe to change while
+// connecting.
+func (cm *ConnectionManager) SetConnectObserver(fn func(ConnectPhases)) {
+	cm.onConnect = fn
+}
+
+// dialPhases is like dial but times each phase of the connect using pgconn's
+// dial and handshake hooks. With fallback hosts the last attempt's phases win.
+func dialPhases(ctx context.Context, connString string) (*pgx.Conn, ConnectPhases, error) {
+	var phases ConnectPhases
+
+	config, err := pgx.ParseConfig(connString)
+	if err != nil {
+		return nil, phases, redactError(err, connString)
+	}
+	timePhases(config, &phases)
+
+	conn, err := pgx.ConnectConfig(ctx, config)
+	return conn, phases, redactError(err, connString)
+}
+
+// timePhases installs hooks on config that fill in phases' Dial, TLS, and
+// Startup as a connect made with it progresses
+func timePhases(config *pgx.ConnConfig, phases *ConnectPhases) {
+	var mark time.Time
+
+	netDial := config.DialFunc
+	config.DialFunc = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		mark = time.Now()
+		conn, err := netDial(ctx, network, addr)
+		phases.Dial = time.Since(mark)
+		phases.TLS = 0
+		mark = time.Now()
+		return conn, err
+	}
+
+	// pgconn wraps the connection in TLS lazily; force the handshake here so
+	// it is not folded into the startup message
+	afterNetConnect := config.AfterNetConnect
+	config.AfterNetConnect = func(ctx context.Context, pc *pgconn.Config, conn net.Conn) (net.Conn, error) {
+		if tlsConn, ok := conn.(*tls.Conn); ok {
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				return conn, err
+			}
+			phases.TLS = time.Since(mark)
+			mark = time.Now()
+		}
+		if afterNetConnect != nil {
+			return afterNetConnect(ctx, pc, conn)
+		}
+		return conn, nil
+	}
+
+	validateConnect := config.ValidateConnect
+	config.ValidateConnect = func(ctx context.Context, pgConn *pgconn.PgConn) error {
+		phases.Startup = time.Since(mark)
+		if validateConnect != nil {
+			return validateConnect(ctx, pgConn)
+		}
+		return nil
+	}
+}
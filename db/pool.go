@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// errNoPool is returned by Acquire when no shared pool is open
+var errNoPool = errors.New("connection pool is not open")
+
+// OpenPool opens a shared pgxpool of up to size connections, for workers
+// that borrow a connection per query instead of holding their own.
+// Connections are opened lazily, counted as active while open, and timed
+// for the connect observer. The pool's size caps them, so they skip the
+// connection limit and dial concurrency cap that Connect waits on. Any
+// previously open pool is closed.
+func (cm *ConnectionManager) OpenPool(size int) error {
+	config, err := pgxpool.ParseConfig(cm.connString)
+	if err != nil {
+		return redactError(err, cm.connString)
+	}
+	config.MaxConns = int32(max(size, 1))
+	if cm.onConnect != nil {
+		config.BeforeConnect = cm.timePoolConnect
+	}
+	config.AfterConnect = func(context.Context, *pgx.Conn) error {
+		cm.activeConnections.Add(1)
+		cm.totalCreated.Add(1)
+		return nil
+	}
+	config.BeforeClose = func(*pgx.Conn) {
+		cm.activeConnections.Add(-1)
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		return redactError(err, cm.connString)
+	}
+	if old := cm.pool.Swap(pool); old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// ClosePool closes the shared pool, waiting for borrowed connections to be
+// released. It is a no-op when no pool is open.
+func (cm *ConnectionManager) ClosePool() {
+	if pool := cm.pool.Swap(nil); pool != nil {
+		pool.Close()
+	}
+}
+
+// Acquire borrows a connection from the shared pool; release it with
+// Release on the returned connection
+func (cm *ConnectionManager) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+	pool := cm.pool.Load()
+	if pool == nil {
+		return nil, errNoPool
+	}
+	start := time.Now()
+	cm.poolWaiting.Add(1)
+	conn, err := pool.Acquire(ctx)
+	cm.poolWaiting.Add(-1)
+	cm.poolWaitNs.Add(int64(time.Since(start)))
+	cm.poolWaits.Add(1)
+	if err != nil {
+		err = redactError(err, cm.connString)
+		if ctx.Err() == nil {
+			cm.recordFailure(err)
+		}
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	return conn, nil
+}
+
+// timePoolConnect times a connect the shared pool is about to make, passing
+// its phases to the connect observer once it succeeds. pgxpool hands each
+// connect its own copy of the config, so the hooks don't collide.
+func (cm *ConnectionManager) timePoolConnect(_ context.Context, config *pgx.ConnConfig) error {
+	start := time.Now()
+	phases := &ConnectPhases{}
+	timePhases(config, phases)
+
+	validateConnect := config.ValidateConnect
+	config.ValidateConnect = func(ctx context.Context, pgConn *pgconn.PgConn) error {
+		if err := validateConnect(ctx, pgConn); err != nil {
+			return err
+		}
+		phases.Total = time.Since(start)
+		cm.onConnect(*phases)
+		return nil
+	}
+	return nil
+}
+
+// IdleConnections returns how many shared or app pool connections are open
+// but not borrowed
+func (cm *ConnectionManager) IdleConnections() int32 {
+	idle := cm.appIdle.Load()
+	if pool := cm.pool.Load(); pool != nil {
+		idle += pool.Stat().IdleConns()
+	}
+	return idle
+}
+
+// AddIdle adjusts the count of connections an app-side pool holds open but
+// unused, for IdleConnections
+func (cm *ConnectionManager) AddIdle(delta int32) {
+	cm.appIdle.Add(delta)
+}
+
+// AddWaiting adjusts the count of callers blocked waiting for an app-side
+// pool connection, for WaitingConnections
+func (cm *ConnectionManager) AddWaiting(delta int32) {
+	cm.poolWaiting.Add(delta)
+}
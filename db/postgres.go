@@ -3,10 +3,13 @@ package db
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"strconv"
 	"sync/atomic"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // ConnectionManager tracks active connections
@@ -15,37 +18,210 @@ type ConnectionManager struct {
 	activeConnections atomic.Int32
 	totalCreated      atomic.Int64
 	totalFailed       atomic.Int64
+
+	// Hard ceiling on concurrent connections opened through Connect
+	limiter *connLimiter
+
+	// Caps simultaneous in-progress dials (nil = unlimited)
+	dialSlots chan struct{}
+	dialWaits atomic.Int64 // Connects that had to wait for a dial slot
+
+	// Receives the phase breakdown of each successful connect (nil = not timed)
+	onConnect func(ConnectPhases)
+
+	// Shared pool for pool mode (nil = workers hold their own connections)
+	pool atomic.Pointer[pgxpool.Pool]
+
+	// Pool-side counts: callers waiting to borrow a connection from the
+	// shared pool or an app pool, and app pool connections parked unused
+	poolWaiting atomic.Int32
+	appIdle     atomic.Int32
+
+	// Time spent borrowing from the shared pool since the last
+	// TakePoolAcquireWait, kept apart from the connection limiter's waits
+	poolWaitNs atomic.Int64
+	poolWaits  atomic.Int64
+
+	// Connection failures are logged every failureLogEvery failures (0 =
+	// never), plus whenever the reason changes; the latest is kept for the API
+	failureLogEvery int64
+	lastFailure     atomic.Pointer[ConnectFailure]
+}
+
+// ConnectFailure is the most recent failed connection attempt
+type ConnectFailure struct {
+	Error string    `json:"error"` // Password redacted
+	At    time.Time `json:"at"`
 }
 
+// defaultFailureLogEvery is how often connection failures are logged when
+// not configured
+const defaultFailureLogEvery = 100
+
 // NewConnectionManager creates a new connection manager
 func NewConnectionManager(connString string) *ConnectionManager {
 	return &ConnectionManager{
-		connString: connString,
+		connString:      connString,
+		limiter:         newConnLimiter(),
+		failureLogEvery: defaultFailureLogEvery,
+	}
+}
+
+// SetFailureLogSample logs every nth connection failure (1 = all, 0 = only
+// when the reason changes). Call before any Connect.
+func (cm *ConnectionManager) SetFailureLogSample(n int) {
+	cm.failureLogEvery = int64(max(n, 0))
+}
+
+// LastConnectError returns the most recent failed connection attempt, or
+// nil if none has failed
+func (cm *ConnectionManager) LastConnectError() *ConnectFailure {
+	return cm.lastFailure.Load()
+}
+
+// SetConnectionLimit caps the number of concurrent connections; Connect blocks
+// until a slot frees. Zero removes the cap.
+func (cm *ConnectionManager) SetConnectionLimit(n int) {
+	cm.limiter.setLimit(n)
+}
+
+// SetDialConcurrency caps how many connection attempts may be in progress at
+// once, smoothing reconnect storms under churn. Zero removes the cap. Call
+// before any Connect; it is not safe to change while connecting.
+func (cm *ConnectionManager) SetDialConcurrency(n int) {
+	if n <= 0 {
+		cm.dialSlots = nil
+		return
 	}
+	cm.dialSlots = make(chan struct{}, n)
+}
+
+// TakeDialWaits returns how many connects waited for a dial slot since the
+// previous call, and resets the count
+func (cm *ConnectionManager) TakeDialWaits() int64 {
+	return cm.dialWaits.Swap(0)
 }
 
-// Connect creates a new direct connection to the database
+// acquireDialSlot blocks until fewer than the configured number of dials are in progress
+func (cm *ConnectionManager) acquireDialSlot(ctx context.Context) error {
+	if cm.dialSlots == nil {
+		return nil
+	}
+	select {
+	case cm.dialSlots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	cm.dialWaits.Add(1)
+	select {
+	case cm.dialSlots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (cm *ConnectionManager) releaseDialSlot() {
+	if cm.dialSlots != nil {
+		<-cm.dialSlots
+	}
+}
+
+// Connect creates a new direct connection to the database, waiting for a slot
+// if the connection limit has been reached
 func (cm *ConnectionManager) Connect(ctx context.Context) (*pgx.Conn, error) {
-	conn, err := pgx.Connect(ctx, cm.connString)
+	return cm.ConnectTo(ctx, cm.connString)
+}
+
+// ConnectTo is like Connect but dials connString (e.g. a regional read
+// endpoint) while sharing the same connection limit and counters
+func (cm *ConnectionManager) ConnectTo(ctx context.Context, connString string) (*pgx.Conn, error) {
+	if err := cm.limiter.acquire(ctx); err != nil {
+		return nil, err
+	}
+	if err := cm.acquireDialSlot(ctx); err != nil {
+		cm.limiter.release()
+		return nil, err
+	}
+
+	// Time the connect only once both slots are held, so queueing behind
+	// the connection limit or other dials isn't counted as connect latency
+	start := time.Now()
+	var conn *pgx.Conn
+	var phases ConnectPhases
+	var err error
+	if cm.onConnect != nil {
+		conn, phases, err = dialPhases(ctx, connString)
+	} else {
+		conn, err = dial(ctx, connString)
+	}
+	phases.Total = time.Since(start)
+	cm.releaseDialSlot()
 	if err != nil {
-		cm.totalFailed.Add(1)
-		if cm.totalFailed.Load()%100 == 1 {
-			log.Printf("Connection failed (total failures: %d): %v", cm.totalFailed.Load(), err)
-		}
+		cm.limiter.release()
+		cm.recordFailure(redactError(err, connString))
 		return nil, fmt.Errorf("failed to connect: %w", err)
 	}
+	if cm.onConnect != nil {
+		cm.onConnect(phases)
+	}
 	cm.activeConnections.Add(1)
 	cm.totalCreated.Add(1)
 	if cm.totalCreated.Load()%1000 == 0 {
-		log.Printf("Connections: active=%d, total_created=%d, total_failed=%d",
-			cm.activeConnections.Load(), cm.totalCreated.Load(), cm.totalFailed.Load())
+		slog.Info("Connections",
+			"active_connections", cm.activeConnections.Load(),
+			"total_created", cm.totalCreated.Load(),
+			"total_failed", cm.totalFailed.Load())
 	}
 	return conn, nil
 }
 
-// Release decrements the connection counter (call when closing a connection)
+// recordFailure counts a failed connect, keeps it as the latest failure, and
+// logs it if sampled or if its reason differs from the previous failure
+func (cm *ConnectionManager) recordFailure(err error) {
+	failed := cm.totalFailed.Add(1)
+	failure := &ConnectFailure{Error: err.Error(), At: time.Now()}
+	previous := cm.lastFailure.Swap(failure)
+
+	sampled := cm.failureLogEvery > 0 && failed%cm.failureLogEvery == 1%cm.failureLogEvery
+	if !sampled && previous != nil && previous.Error == failure.Error {
+		return
+	}
+	slog.Warn("Connection failed",
+		"active_connections", cm.activeConnections.Load(),
+		"total_created", cm.totalCreated.Load(),
+		"total_failed", failed,
+		"error", failure.Error)
+}
+
+// Release decrements the connection counter and frees its slot (call when closing a connection)
 func (cm *ConnectionManager) Release() {
 	cm.activeConnections.Add(-1)
+	cm.limiter.release()
+}
+
+// WaitingConnections returns how many callers are blocked waiting for a
+// connection slot or to borrow a pooled connection
+func (cm *ConnectionManager) WaitingConnections() int32 {
+	return cm.limiter.waiting.Load() + cm.poolWaiting.Load()
+}
+
+// TakeAcquireWait returns the average time Connect spent waiting for a slot
+// since the previous call, and resets the average
+func (cm *ConnectionManager) TakeAcquireWait() time.Duration {
+	return cm.limiter.takeWait()
+}
+
+// TakePoolAcquireWait returns the average time Acquire spent borrowing a
+// connection from the shared pool since the previous call, and resets it
+func (cm *ConnectionManager) TakePoolAcquireWait() time.Duration {
+	n := cm.poolWaits.Swap(0)
+	total := cm.poolWaitNs.Swap(0)
+	if n == 0 {
+		return 0
+	}
+	return time.Duration(total / n)
 }
 
 // ActiveConnections returns the current count of active connections
@@ -55,10 +231,129 @@ func (cm *ConnectionManager) ActiveConnections() int32 {
 
 // Ping verifies connectivity to the database
 func (cm *ConnectionManager) Ping(ctx context.Context) error {
-	conn, err := pgx.Connect(ctx, cm.connString)
+	conn, err := dial(ctx, cm.connString)
 	if err != nil {
 		return fmt.Errorf("failed to connect: %w", err)
 	}
 	defer conn.Close(ctx)
 	return conn.Ping(ctx)
 }
+
+// Warmup runs query n times on a dedicated connection to connString (""
+// for the primary) with args() as its arguments, to prime caches and plans
+// before a run. It bypasses the connection limit and counters.
+func (cm *ConnectionManager) Warmup(ctx context.Context, connString, query string, n int, args func() []any) error {
+	if connString == "" {
+		connString = cm.connString
+	}
+	conn, err := dial(ctx, connString)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	for i := 0; i < n; i++ {
+		if _, err := conn.Exec(ctx, query, args()...); err != nil {
+			return fmt.Errorf("warmup query %d of %d failed: %w", i+1, n, err)
+		}
+	}
+	return nil
+}
+
+// ServerLimits describes the connection headroom reported by the server when
+// it was probed; it is a snapshot and isn't refreshed as connections come and go
+type ServerLimits struct {
+	MaxConnections       int       `json:"server_max_connections"`
+	CurrentConnections   int       `json:"current_connections"`   // Client backends only
+	ReservedConnections  int       `json:"reserved_connections"`  // Slots this role can't use (0 for superusers)
+	AvailableConnections int       `json:"available_connections"` // Slots left for this role
+	ProbedAt             time.Time `json:"server_limits_probed_at"`
+}
+
+// ProbeServerLimits queries max_connections, the reserved slots, and
+// pg_stat_activity to compute how many more connections the server would
+// accept from this role
+func (cm *ConnectionManager) ProbeServerLimits(ctx context.Context) (ServerLimits, error) {
+	conn, err := dial(ctx, cm.connString)
+	if err != nil {
+		return ServerLimits{}, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	var limits ServerLimits
+	var maxConns string
+	if err := conn.QueryRow(ctx, "SHOW max_connections").Scan(&maxConns); err != nil {
+		return ServerLimits{}, fmt.Errorf("failed to read max_connections: %w", err)
+	}
+	limits.MaxConnections, err = strconv.Atoi(maxConns)
+	if err != nil {
+		return ServerLimits{}, fmt.Errorf("invalid max_connections %q: %w", maxConns, err)
+	}
+
+	// Background workers and autovacuum don't take max_connections slots
+	if err := conn.QueryRow(ctx, "SELECT count(*) FROM pg_stat_activity WHERE backend_type = 'client backend'").Scan(&limits.CurrentConnections); err != nil {
+		return ServerLimits{}, fmt.Errorf("failed to count connections: %w", err)
+	}
+
+	// Non-superusers can't use the superuser slots, nor (from PG16)
+	// reserved_connections, which is NULL on older servers
+	err = conn.QueryRow(ctx, `
+		SELECT CASE WHEN current_setting('is_superuser') = 'on' THEN 0
+			ELSE current_setting('superuser_reserved_connections')::int
+				+ COALESCE(current_setting('reserved_connections', true)::int, 0)
+		END`).Scan(&limits.ReservedConnections)
+	if err != nil {
+		return ServerLimits{}, fmt.Errorf("failed to read reserved connections: %w", err)
+	}
+
+	limits.AvailableConnections = max(limits.MaxConnections-limits.ReservedConnections-limits.CurrentConnections, 0)
+	limits.ProbedAt = time.Now()
+	return limits, nil
+}
+
+// ServerVersion returns the server_version reported by the database
+func (cm *ConnectionManager) ServerVersion(ctx context.Context) (string, error) {
+	conn, err := dial(ctx, cm.connString)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	var version string
+	if err := conn.QueryRow(ctx, "SHOW server_version").Scan(&version); err != nil {
+		return "", fmt.Errorf("failed to read server_version: %w", err)
+	}
+	return version, nil
+}
+
+// CheckTablespace verifies that tablespace exists and reports the tablespace
+// table actually lives on, resolving the database default to its name (e.g.
+// pg_default) so it can be compared with tablespace
+func (cm *ConnectionManager) CheckTablespace(ctx context.Context, tablespace, table string) (string, error) {
+	conn, err := dial(ctx, cm.connString)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_tablespace WHERE spcname = $1)", tablespace).Scan(&exists); err != nil {
+		return "", fmt.Errorf("failed to look up tablespace: %w", err)
+	}
+	if !exists {
+		return "", fmt.Errorf("tablespace %q does not exist", tablespace)
+	}
+
+	// reltablespace is 0 for tables on the database's default tablespace
+	var actual string
+	err = conn.QueryRow(ctx, `
+		SELECT t.spcname
+		FROM pg_class c
+		JOIN pg_tablespace t ON t.oid = COALESCE(NULLIF(c.reltablespace, 0),
+			(SELECT dattablespace FROM pg_database WHERE datname = current_database()))
+		WHERE c.oid = to_regclass($1)`, pgx.Identifier{table}.Sanitize()).Scan(&actual)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up table %q: %w", table, err)
+	}
+	return actual, nil
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// ConnStringCheck is the outcome of CheckConnString
+type ConnStringCheck struct {
+	OK            bool    `json:"ok"`
+	ConnectMs     float64 `json:"connect_ms,omitempty"`
+	QueryMs       float64 `json:"query_ms,omitempty"`
+	ServerVersion string  `json:"server_version,omitempty"`
+	Error         string  `json:"error,omitempty"` // Password redacted
+}
+
+// CheckConnString connects to connString, runs SELECT 1, and reports timings
+// and the server version. It does not touch any ConnectionManager.
+func CheckConnString(ctx context.Context, connString string) ConnStringCheck {
+	var result ConnStringCheck
+
+	start := time.Now()
+	conn, err := dial(ctx, connString)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer conn.Close(context.Background())
+	result.ConnectMs = float64(time.Since(start).Microseconds()) / 1000.0
+
+	start = time.Now()
+	var one int
+	if err := conn.QueryRow(ctx, "SELECT 1").Scan(&one); err != nil {
+		result.Error = redactError(err, connString).Error()
+		return result
+	}
+	result.QueryMs = float64(time.Since(start).Microseconds()) / 1000.0
+
+	// Poolers may not support SHOW; the version is best effort
+	if err := conn.QueryRow(ctx, "SHOW server_version").Scan(&result.ServerVersion); err != nil {
+		result.ServerVersion = ""
+	}
+
+	result.OK = true
+	return result
+}
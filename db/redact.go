@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const redactedPassword = "xxxxx"
+
+var (
+	// password=secret or password='secret' in keyword/value connection strings
+	keywordPasswordRe = regexp.MustCompile(`(?i)(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+	// user:secret@ in URL connection strings
+	urlPasswordRe = regexp.MustCompile(`(://[^:/@\s]*:)[^@\s]*@`)
+)
+
+// RedactConnString masks any password in a URL or keyword/value connection
+// string so it is safe to log or return from the API
+func RedactConnString(s string) string {
+	s = urlPasswordRe.ReplaceAllString(s, "${1}"+redactedPassword+"@")
+	return keywordPasswordRe.ReplaceAllString(s, "${1}"+redactedPassword)
+}
+
+// redactedError hides a connection string's password in err's message while
+// keeping the original error available to errors.Is/As
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redactError returns err with connString's password masked in its message
+func redactError(err error, connString string) error {
+	if err == nil {
+		return nil
+	}
+	msg := RedactConnString(err.Error())
+	if password := connStringPassword(connString); password != "" {
+		msg = strings.ReplaceAll(msg, password, redactedPassword)
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
+
+// connStringPassword extracts the password from a URL connection string, if any
+func connStringPassword(connString string) string {
+	u, err := url.Parse(connString)
+	if err != nil || u.User == nil {
+		return ""
+	}
+	password, _ := u.User.Password()
+	return password
+}
+
+// dial opens a connection, masking the password in any error it returns
+func dial(ctx context.Context, connString string) (*pgx.Conn, error) {
+	conn, err := pgx.Connect(ctx, connString)
+	return conn, redactError(err, connString)
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrStatementsUnavailable is returned when pg_stat_statements is not installed
+var ErrStatementsUnavailable = errors.New("pg_stat_statements extension is not installed")
+
+// StatementStat holds pg_stat_statements counters for a single query
+type StatementStat struct {
+	Query       string  `json:"query"`
+	Calls       int64   `json:"calls"`
+	Rows        int64   `json:"rows"`
+	TotalTimeMs float64 `json:"total_time_ms"`
+	MeanTimeMs  float64 `json:"mean_time_ms"`
+}
+
+// StatementStats returns pg_stat_statements entries whose query text exactly
+// matches one of queries, aggregated across users and databases
+func (cm *ConnectionManager) StatementStats(ctx context.Context, queries []string) ([]StatementStat, error) {
+	conn, err := dial(ctx, cm.connString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	var installed bool
+	if err := conn.QueryRow(ctx, "SELECT to_regclass('pg_stat_statements') IS NOT NULL").Scan(&installed); err != nil {
+		return nil, fmt.Errorf("failed to check for pg_stat_statements: %w", err)
+	}
+	if !installed {
+		return nil, ErrStatementsUnavailable
+	}
+
+	rows, err := conn.Query(ctx, `
+		SELECT query, sum(calls)::bigint, sum(rows)::bigint, sum(total_exec_time)::float8
+		FROM pg_stat_statements
+		WHERE query = ANY($1)
+		GROUP BY query
+		ORDER BY 4 DESC`, queries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pg_stat_statements: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []StatementStat
+	for rows.Next() {
+		var s StatementStat
+		if err := rows.Scan(&s.Query, &s.Calls, &s.Rows, &s.TotalTimeMs); err != nil {
+			return nil, fmt.Errorf("failed to scan pg_stat_statements: %w", err)
+		}
+		if s.Calls > 0 {
+			s.MeanTimeMs = s.TotalTimeMs / float64(s.Calls)
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TableStats holds pg_stat_user_tables counters for the workload table
+type TableStats struct {
+	Table           string     `json:"table"`
+	LiveTuples      int64      `json:"live_tuples"`
+	DeadTuples      int64      `json:"dead_tuples"`
+	LastAutovacuum  *time.Time `json:"last_autovacuum,omitempty"`
+	LastAutoanalyze *time.Time `json:"last_autoanalyze,omitempty"`
+	AutovacuumCount int64      `json:"autovacuum_count"`
+	SampledAt       int64      `json:"sampled_at"` // Unix milliseconds
+
+	// Change in dead tuples per second since the previous sample (negative
+	// after a vacuum)
+	DeadTuplesPerSec float64 `json:"dead_tuples_per_sec"`
+}
+
+// MonitorTable samples pg_stat_user_tables for table every interval on a
+// dedicated connection and passes each sample to report. It reconnects after
+// errors and returns when ctx is done.
+func (cm *ConnectionManager) MonitorTable(ctx context.Context, table string, interval time.Duration, report func(TableStats)) {
+	var prev TableStats
+	cm.poll(ctx, "Table monitor", interval, func(ctx context.Context, conn *pgx.Conn) error {
+		stats, err := sampleTableStats(ctx, conn, table)
+		if err != nil {
+			return err
+		}
+		if prev.SampledAt != 0 && stats.SampledAt > prev.SampledAt {
+			elapsed := float64(stats.SampledAt-prev.SampledAt) / 1000
+			stats.DeadTuplesPerSec = float64(stats.DeadTuples-prev.DeadTuples) / elapsed
+		}
+		prev = stats
+		report(stats)
+		return nil
+	})
+}
+
+// SetAutovacuum turns autovacuum off for table, so dead tuples accumulate
+// unchecked, or back to the server default. This alters the table.
+func (cm *ConnectionManager) SetAutovacuum(ctx context.Context, table string, enabled bool) error {
+	conn, err := dial(ctx, cm.connString)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close(ctx)
+
+	quoted := pgx.Identifier{table}.Sanitize()
+	sql := fmt.Sprintf("ALTER TABLE %s SET (autovacuum_enabled = false, toast.autovacuum_enabled = false)", quoted)
+	if enabled {
+		sql = fmt.Sprintf("ALTER TABLE %s RESET (autovacuum_enabled, toast.autovacuum_enabled)", quoted)
+	}
+	if _, err := conn.Exec(ctx, sql); err != nil {
+		return fmt.Errorf("failed to alter %s: %w", table, err)
+	}
+	return nil
+}
+
+// poll calls sample every interval on a dedicated connection, reconnecting
+// after errors, until ctx is done. name labels logged errors.
+func (cm *ConnectionManager) poll(ctx context.Context, name string, interval time.Duration, sample func(context.Context, *pgx.Conn) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var conn *pgx.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close(context.Background())
+		}
+	}()
+
+	for {
+		if conn == nil {
+			var err error
+			conn, err = dial(ctx, cm.connString)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Warn("Monitor failed to connect", "monitor", name, "error", err)
+				}
+				conn = nil
+			}
+		}
+
+		if conn != nil {
+			if err := sample(ctx, conn); err != nil {
+				if ctx.Err() == nil {
+					slog.Warn("Monitor sample failed", "monitor", name, "error", err)
+				}
+				conn.Close(context.Background())
+				conn = nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func sampleTableStats(ctx context.Context, conn *pgx.Conn, table string) (TableStats, error) {
+	stats := TableStats{Table: table}
+	err := conn.QueryRow(ctx, `
+		SELECT n_live_tup, n_dead_tup, last_autovacuum, last_autoanalyze, autovacuum_count
+		FROM pg_stat_user_tables
+		WHERE relid = to_regclass($1)`, pgx.Identifier{table}.Sanitize(),
+	).Scan(&stats.LiveTuples, &stats.DeadTuples, &stats.LastAutovacuum, &stats.LastAutoanalyze, &stats.AutovacuumCount)
+	if err != nil {
+		return TableStats{}, fmt.Errorf("failed to read stats for %s: %w", table, err)
+	}
+	stats.SampledAt = time.Now().UnixMilli()
+	return stats, nil
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// WALStats holds the server's WAL generation rate between two samples
+type WALStats struct {
+	LSN         string  `json:"lsn"`
+	BytesPerSec float64 `json:"bytes_per_sec"`
+	TotalBytes  int64   `json:"total_bytes"` // Generated since monitoring began
+	SampledAt   int64   `json:"sampled_at"`  // Unix milliseconds
+}
+
+// MonitorWAL samples pg_current_wal_lsn() every interval on a dedicated
+// connection and reports WAL bytes/sec since the previous sample. The first
+// sample only establishes a baseline. Returns when ctx is done.
+func (cm *ConnectionManager) MonitorWAL(ctx context.Context, interval time.Duration, report func(WALStats)) {
+	var start, prev int64
+	var prevAt time.Time
+
+	cm.poll(ctx, "WAL monitor", interval, func(ctx context.Context, conn *pgx.Conn) error {
+		lsn, pos, err := sampleWALPosition(ctx, conn)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+
+		if !prevAt.IsZero() {
+			var rate float64
+			if elapsed := now.Sub(prevAt).Seconds(); elapsed > 0 {
+				rate = float64(pos-prev) / elapsed
+			}
+			report(WALStats{
+				LSN:         lsn,
+				BytesPerSec: rate,
+				TotalBytes:  pos - start,
+				SampledAt:   now.UnixMilli(),
+			})
+		} else {
+			start = pos
+		}
+		prev, prevAt = pos, now
+		return nil
+	})
+}
+
+// sampleWALPosition returns the current WAL insert LSN and its byte offset
+func sampleWALPosition(ctx context.Context, conn *pgx.Conn) (string, int64, error) {
+	var lsn string
+	var pos int64
+	err := conn.QueryRow(ctx,
+		"SELECT pg_current_wal_lsn()::text, pg_wal_lsn_diff(pg_current_wal_lsn(), '0/0')::bigint",
+	).Scan(&lsn, &pos)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read WAL position: %w", err)
+	}
+	return lsn, pos, nil
+}
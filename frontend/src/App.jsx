@@ -17,6 +17,7 @@ function App() {
     churn_rate: 0,
   });
   const [running, setRunning] = useState(false);
+  const [limits, setLimits] = useState({});
   const [latestMetrics, setLatestMetrics] = useState(null);
   const [recentErrors, setRecentErrors] = useState([]);
 
@@ -34,6 +35,7 @@ function App() {
     getStatus().then((status) => {
       setRunning(status.running);
       setConfig(status.config);
+      setLimits(status.limits || {});
     }).catch(console.error);
   }, []);
 
@@ -97,7 +99,7 @@ function App() {
       <header className="bg-slate-800 border-b border-slate-700 px-6 py-4">
         <div className="max-w-7xl mx-auto flex items-center justify-between">
           <div className="flex items-center gap-3">
-            <img src="/logo.png" alt="SupaFirehose" className="h-10 w-10" />
+            <img src={`${import.meta.env.BASE_URL}logo.png`} alt="SupaFirehose" className="h-10 w-10" />
             <h1 className="text-2xl font-bold text-white">SUPAFIREHOSE</h1>
           </div>
           <div className="flex items-center gap-4">
@@ -123,6 +125,7 @@ function App() {
           <div className="lg:col-span-1">
             <ControlPanel
               config={config}
+              limits={limits}
               running={running}
               onConfigChange={handleConfigChange}
               onStart={handleStart}
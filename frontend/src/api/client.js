@@ -1,4 +1,7 @@
-const API_BASE = '/api';
+// Path prefix injected by the server when mounted under BASE_PATH
+export const BASE_PATH = window.__BASE_PATH__ || '';
+
+const API_BASE = `${BASE_PATH}/api`;
 
 export async function getStatus() {
   const response = await fetch(`${API_BASE}/status`);
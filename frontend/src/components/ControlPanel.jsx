@@ -1,6 +1,11 @@
 import { useState, useEffect } from 'react';
 
-export function ControlPanel({ config, running, onConfigChange, onStart, onStop, onReset }) {
+// cap bounds a slider's max by a server limit (0 or missing = no limit)
+function cap(max, limit) {
+  return limit > 0 ? Math.min(max, limit) : max;
+}
+
+export function ControlPanel({ config, limits = {}, running, onConfigChange, onStart, onStop, onReset }) {
   const [localConfig, setLocalConfig] = useState(config);
 
   useEffect(() => {
@@ -22,7 +27,7 @@ export function ControlPanel({ config, running, onConfigChange, onStart, onStop,
           label="Connections"
           value={localConfig.connections}
           min={1}
-          max={20000}
+          max={cap(20000, limits.max_connections)}
           step={10}
           onChange={(v) => handleSliderChange('connections', v)}
         />
@@ -31,7 +36,7 @@ export function ControlPanel({ config, running, onConfigChange, onStart, onStop,
           label="Read QPS"
           value={localConfig.read_qps}
           min={0}
-          max={25000}
+          max={cap(25000, limits.max_read_qps)}
           step={100}
           onChange={(v) => handleSliderChange('read_qps', v)}
         />
@@ -40,7 +45,7 @@ export function ControlPanel({ config, running, onConfigChange, onStart, onStop,
           label="Write QPS"
           value={localConfig.write_qps}
           min={0}
-          max={5000}
+          max={cap(5000, limits.max_write_qps)}
           step={50}
           onChange={(v) => handleSliderChange('write_qps', v)}
         />
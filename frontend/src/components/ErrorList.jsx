@@ -29,6 +29,11 @@ function ErrorListInner({ errors }) {
                   <span className="text-red-300 font-mono text-xs break-all">
                     {error.message}
                   </span>
+                  {error.count > 1 && (
+                    <span className="text-red-400 font-mono text-xs whitespace-nowrap">
+                      ×{error.count}
+                    </span>
+                  )}
                 </div>
               </div>
             ))}
@@ -1,5 +1,5 @@
 import { memo } from 'react';
-import { formatNumber, formatPercent } from '../utils/formatting';
+import { formatBytes, formatNumber, formatPercent } from '../utils/formatting';
 
 function StatsPanelInner({ metrics }) {
   if (!metrics) {
@@ -18,6 +18,7 @@ function StatsPanelInner({ metrics }) {
         <StatCard
           label="Total Queries"
           value={formatNumber(metrics.totals.queries)}
+          detail={`${formatBytes(metrics.totals.read_bytes || 0)} read, ${formatBytes(metrics.totals.write_bytes || 0)} written`}
         />
         <StatCard
           label="Total Errors"
@@ -32,19 +33,21 @@ function StatsPanelInner({ metrics }) {
         <StatCard
           label="Active Connections"
           value={`${metrics.pool.active_connections}`}
+          detail={`${metrics.pool.idle_connections || 0} idle, ${metrics.pool.waiting_requests || 0} waiting`}
         />
       </div>
     </div>
   );
 }
 
-const StatCard = memo(function StatCard({ label, value, variant = 'default' }) {
+const StatCard = memo(function StatCard({ label, value, detail, variant = 'default' }) {
   const valueColor = variant === 'error' ? 'text-red-400' : 'text-white';
 
   return (
     <div className="bg-slate-700/50 rounded-lg p-4">
       <div className="text-sm text-slate-400 mb-1">{label}</div>
       <div className={`text-2xl font-mono font-bold ${valueColor}`}>{value}</div>
+      {detail && <div className="text-xs text-slate-500 mt-1">{detail}</div>}
     </div>
   );
 });
@@ -1,5 +1,6 @@
 import { useState, useEffect, useRef, useCallback, useMemo } from 'react';
 import { throttle } from 'lodash-es';
+import { BASE_PATH } from '../api/client';
 
 // Throttle interval - updates will be batched to this frequency
 const THROTTLE_MS = 250;
@@ -27,7 +28,7 @@ export function useWebSocket(url) {
 
   const connect = useCallback(() => {
     const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-    const wsUrl = `${protocol}//${window.location.host}${url}`;
+    const wsUrl = `${protocol}//${window.location.host}${BASE_PATH}${url}`;
 
     const ws = new WebSocket(wsUrl);
     wsRef.current = ws;
@@ -25,3 +25,16 @@ export function formatLatency(ms) {
 export function formatPercent(rate) {
   return (rate * 100).toFixed(3) + '%';
 }
+
+export function formatBytes(bytes) {
+  if (bytes >= 1024 * 1024 * 1024) {
+    return (bytes / (1024 * 1024 * 1024)).toFixed(2) + ' GiB';
+  }
+  if (bytes >= 1024 * 1024) {
+    return (bytes / (1024 * 1024)).toFixed(1) + ' MiB';
+  }
+  if (bytes >= 1024) {
+    return (bytes / 1024).toFixed(1) + ' KiB';
+  }
+  return bytes.toFixed(0) + ' B';
+}
@@ -4,6 +4,8 @@ import tailwindcss from '@tailwindcss/vite'
 
 // https://vite.dev/config/
 export default defineConfig({
+  // Relative asset URLs, so the build works under any BASE_PATH
+  base: './',
   plugins: [react(), tailwindcss()],
   server: {
     proxy: {
@@ -1,19 +1,44 @@
 -- Supafirehose Demo Database Schema
 -- Run: psql -h localhost -U postgres -d pooler_demo -f init.sql
+-- With a table prefix (matching TABLE_PREFIX): add -v table_prefix=sf_
+-- On a specific tablespace (matching TABLESPACE): add -v tablespace=fast_ssd
+-- Without secondary indexes: add -v skip_indexes=1
+-- With autovacuum off (dead tuples accumulate): add -v autovacuum_off=1
+
+\if :{?table_prefix}
+\else
+\set table_prefix ''
+\endif
+\set users_table :table_prefix users
+\set users_email_index :users_table _email_key
+\if :{?tablespace}
+\set tablespace_clause 'TABLESPACE ' :"tablespace"
+\else
+\set tablespace_clause ''
+\endif
 
 -- Create users table for read/write operations
-CREATE TABLE IF NOT EXISTS users (
+CREATE TABLE IF NOT EXISTS :"users_table" (
     id         BIGSERIAL PRIMARY KEY,
     username   VARCHAR(255) NOT NULL,
     email      VARCHAR(255) NOT NULL,
     created_at TIMESTAMPTZ  NOT NULL DEFAULT NOW()
-);
+) :tablespace_clause;
+
+-- The primary key already creates a unique index on id.
+-- Add the secondary index a real app would have so reads and writes pay for it.
+\if :{?skip_indexes}
+\else
+CREATE UNIQUE INDEX IF NOT EXISTS :"users_email_index" ON :"users_table" (email);
+\endif
 
--- Create index on id (primary key handles this, but being explicit)
--- The primary key already creates a unique index
+-- Worst-case bloat runs: stop autovacuum from cleaning up dead tuples.
+\if :{?autovacuum_off}
+ALTER TABLE :"users_table" SET (autovacuum_enabled = false, toast.autovacuum_enabled = false);
+\endif
 
 -- Seed with 100,000 users for read operations
-INSERT INTO users (username, email)
+INSERT INTO :"users_table" (username, email)
 SELECT
     'user_' || i,
     'user_' || i || '@example.com'
@@ -21,4 +46,4 @@ FROM generate_series(1, 100000) AS i
 ON CONFLICT DO NOTHING;
 
 -- Analyze table for query planner
-ANALYZE users;
+ANALYZE :"users_table";
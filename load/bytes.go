@@ -0,0 +1,21 @@
+package load
+
+import "time"
+
+// valueBytes roughly estimates the bytes query arguments or scanned values
+// occupy on the wire: the length of strings and byte slices, and 8 bytes for
+// numbers and timestamps. Protocol framing is ignored.
+func valueBytes(values ...any) int {
+	n := 0
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			n += len(v)
+		case []byte:
+			n += len(v)
+		case int, int64, float64, time.Time:
+			n += 8
+		}
+	}
+	return n
+}
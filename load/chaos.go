@@ -0,0 +1,115 @@
+package load
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// connHandle exposes a worker's current persistent connection so the chaos
+// loop can sever it from outside. A nil handle is a no-op.
+type connHandle struct {
+	conn      atomic.Pointer[pgx.Conn]
+	severedAt atomic.Int64 // Unix nanoseconds of the last sever not yet recovered from
+}
+
+// set records a newly opened connection and returns how long the worker took
+// to reconnect if its previous connection was severed (0 otherwise)
+func (h *connHandle) set(conn *pgx.Conn) time.Duration {
+	if h == nil {
+		return 0
+	}
+	h.conn.Store(conn)
+	if severedAt := h.severedAt.Swap(0); severedAt != 0 {
+		return time.Duration(time.Now().UnixNano() - severedAt)
+	}
+	return 0
+}
+
+func (h *connHandle) clear() {
+	if h != nil {
+		h.conn.Store(nil)
+	}
+}
+
+// sever closes the underlying network connection out from under the worker,
+// like a network blip or pooler restart, so its next query fails and it
+// reconnects. Reports whether there was a connection to sever.
+func (h *connHandle) sever() bool {
+	conn := h.conn.Swap(nil)
+	if conn == nil {
+		return false
+	}
+	h.severedAt.Store(time.Now().UnixNano())
+	conn.PgConn().Conn().Close()
+	return true
+}
+
+// handleSet holds the handles of the running workers the chaos loop targets;
+// workers added or removed by a resize join or leave it
+type handleSet struct {
+	mu      sync.Mutex
+	handles []*connHandle
+}
+
+func (s *handleSet) add(h *connHandle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handles = append(s.handles, h)
+}
+
+func (s *handleSet) remove(h *connHandle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handles = slices.DeleteFunc(s.handles, func(other *connHandle) bool { return other == h })
+}
+
+// live returns the handles currently holding a connection
+func (s *handleSet) live() []*connHandle {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var live []*connHandle
+	for _, h := range s.handles {
+		if h.conn.Load() != nil {
+			live = append(live, h)
+		}
+	}
+	return live
+}
+
+// runChaos severs fraction of the workers' live connections all at once every
+// interval until ctx is done, so the affected workers reconnect together
+func (c *Controller) runChaos(ctx context.Context, handles *handleSet, interval time.Duration, fraction float64) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		live := handles.live()
+		if len(live) == 0 {
+			continue
+		}
+		rand.Shuffle(len(live), func(i, j int) { live[i], live[j] = live[j], live[i] })
+
+		severed := 0
+		for _, h := range live[:int(math.Ceil(float64(len(live))*fraction))] {
+			if h.sever() {
+				severed++
+			}
+		}
+		c.collector.RecordChaosSevered(severed)
+		slog.Info("Chaos: severed connections", "severed", severed)
+	}
+}
@@ -0,0 +1,49 @@
+package load
+
+import (
+	"context"
+	"time"
+)
+
+// inflightLimiter caps how many operations of one kind run at once across
+// all workers, modeling an app's internal concurrency limit. A nil limiter
+// is unlimited.
+type inflightLimiter struct {
+	slots  chan struct{}
+	onWait func() // Called when an operation has to wait for a slot
+}
+
+func newInflightLimiter(n int, onWait func()) *inflightLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return &inflightLimiter{slots: make(chan struct{}, n), onWait: onWait}
+}
+
+// acquire blocks until a slot is free or ctx is done, and returns how long
+// it waited so callers can keep the wait out of query latency
+func (l *inflightLimiter) acquire(ctx context.Context) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return 0, nil
+	default:
+	}
+
+	l.onWait()
+	start := time.Now()
+	select {
+	case l.slots <- struct{}{}:
+		return time.Since(start), nil
+	case <-ctx.Done():
+		return time.Since(start), ctx.Err()
+	}
+}
+
+func (l *inflightLimiter) release() {
+	if l != nil {
+		<-l.slots
+	}
+}
@@ -2,7 +2,13 @@ package load
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"supafirehose/db"
 	"supafirehose/metrics"
@@ -16,128 +22,747 @@ type Config struct {
 	ReadQPS     int `json:"read_qps"`
 	WriteQPS    int `json:"write_qps"`
 	ChurnRate   int `json:"churn_rate"` // Connections churned per second
+
+	// Close and reopen each persistent connection after this many seconds,
+	// varied uniformly by up to MaxConnLifetimeJitterPct (0-100) percent
+	// either way, like a pooler's server_lifetime. Overrides the lifetimes
+	// churn_rate would give (0 = use churn_rate).
+	MaxConnLifetimeSec       int `json:"max_conn_lifetime_sec"`
+	MaxConnLifetimeJitterPct int `json:"max_conn_lifetime_jitter_pct"`
+
+	// Alternative to read_qps/write_qps: total QPS split by the fraction (0-1)
+	// of reads, DefaultReadRatio unless set. Only used when read_qps and
+	// write_qps are both 0.
+	TotalQPS  int     `json:"total_qps"`
+	ReadRatio float64 `json:"read_ratio"`
+
+	// Caps on reads/writes in flight at once across all workers, independent
+	// of connections and rate (0 = unlimited)
+	MaxConcurrentReads  int `json:"max_concurrent_reads"`
+	MaxConcurrentWrites int `json:"max_concurrent_writes"`
+
+	// Reads issued on a dedicated connection before workers start, to prime
+	// caches and plans; excluded from metrics
+	WarmupQueries int `json:"warmup_queries"`
+
+	// Run workers for this many seconds on Start before recording anything;
+	// reads and writes in that time are discarded and metrics are then reset,
+	// so connection setup and cold caches don't skew the run
+	WarmupSeconds int `json:"warmup_seconds"`
+
+	// Ramp up from zero over this many seconds on Start: rate limits rise
+	// linearly and workers open their connections staggered across the ramp.
+	// Restarts for config changes don't ramp.
+	RampSeconds int `json:"ramp_seconds"`
+
+	// Fraction (0-1) of queries run under EXPLAIN ANALYZE for plan timing.
+	// They count toward QPS but not the latency histograms.
+	ExplainSampleRate float64 `json:"explain_sample_rate"`
+
+	// Retries for writes failing with serialization failure or deadlock
+	WriteRetries int `json:"write_retries"`
+
+	// Retries for reads and writes that fail because the connection broke
+	// (reset, closed, or admin shutdown): the worker reconnects and reissues
+	// the query after a backoff up to this many times before recording it as
+	// an error, with latency measured from the first attempt. Writes are only
+	// retried if they were never sent, since one that broke mid-flight may
+	// have committed. Only workers holding their own connections retry.
+	TransientRetries int `json:"transient_retries"`
+
+	// Inserts per transaction: writers BEGIN, run this many inserts, then
+	// COMMIT. write_qps then limits transactions, and writes.qps counts commits
+	// while writes.rows_per_sec counts rows. 0 or 1 means autocommit.
+	StatementsPerTransaction int `json:"statements_per_transaction"`
+
+	// Rows per insert statement: writers send one multi-row INSERT and it
+	// counts as a single write, with writes.rows_per_sec counting rows.
+	// 0 or 1 means single-row inserts.
+	WriteBatchSize int `json:"write_batch_size"`
+
+	// What each write does: "insert" (default) adds a new row, and "upsert"
+	// runs INSERT ... ON CONFLICT (id) DO UPDATE, giving an existing ID from
+	// the recent-insert cache to UpsertConflictRate (0-1) of them so the
+	// conflict path fires. Upserts cannot be batched.
+	WriteMode          string  `json:"write_mode"`
+	UpsertConflictRate float64 `json:"upsert_conflict_rate"`
+
+	// Fraction (0-1) of writes deliberately sent with a NULL email so the
+	// server rejects them, for testing error metrics and alerting
+	ErrorInjectionRate float64 `json:"error_injection_rate"`
+
+	// Per-query deadline; reads and writes running longer are cancelled and
+	// recorded as "query timeout" errors (0 = no limit)
+	QueryTimeoutMs int `json:"query_timeout_ms"`
+
+	// On Stop, pause reads and writes and give queries already in flight up
+	// to this long to finish before cancelling the rest, so runs don't end
+	// with queries cut off mid-execution (0 = cancel at once)
+	DrainTimeoutMs int `json:"drain_timeout_ms"`
+
+	// Simulated network latency added before each query (base ± jitter)
+	InjectLatencyMs       int `json:"inject_latency_ms"`
+	InjectLatencyJitterMs int `json:"inject_latency_jitter_ms"`
+
+	// Connect, run one query, and disconnect for every operation (extreme churn)
+	PerQueryConnect bool `json:"per_query_connect"`
+
+	// Prepare each query once per connection, right after connecting, and run
+	// it as that prepared statement from then on, so parsing is paid at
+	// connect time rather than per query. pgx's default statement cache
+	// already does this lazily on first use; this makes it explicit and holds
+	// even when DATABASE_URL picks another exec mode. When off, queries run
+	// unprepared, parsed on every execution, as a baseline. Only workers
+	// holding their own connections prepare.
+	PreparedStatements bool `json:"prepared_statements"`
+
+	// How reads pick IDs: "uniform" (default) over 1..maxID, or "recent" to
+	// favor the last RecentWindow inserted IDs (temporal locality)
+	ReadStrategy string `json:"read_strategy"`
+	RecentWindow int    `json:"recent_window"`
+
+	// What each read fetches: "point" (default) looks up one row by ID,
+	// "range" fetches ReadRangeSize rows (default 100) ordered by ID from a
+	// random offset, iterating and discarding them to exercise scans and
+	// large result sets, and "in_list" looks up ReadInListSize IDs (default
+	// 100) picked by read_strategy in one WHERE id = ANY($1) query. Range
+	// reads ignore read_strategy; the rows fetched are counted in
+	// reads.rows_per_sec.
+	ReadMode       string `json:"read_mode"`
+	ReadRangeSize  int    `json:"read_range_size"`
+	ReadInListSize int    `json:"read_in_list_size"`
+
+	// Two-tier topology: when AppInstances > 0, workers are spread across that
+	// many app instances, each sharing a pool of ConnectionsPerInstance
+	// persistent connections. Connections then sets app-side concurrency.
+	AppInstances           int `json:"app_instances"`
+	ConnectionsPerInstance int `json:"connections_per_instance"`
+
+	// Chaos mode: every ChaosIntervalSec, force-close ChaosFraction (0-1) of
+	// the workers' persistent connections at once, simulating a network blip
+	// or pooler restart. Pooled and per-query connections are not targeted.
+	ChaosIntervalSec int     `json:"chaos_interval_sec"`
+	ChaosFraction    float64 `json:"chaos_fraction"`
+
+	// Pool mode: workers borrow a connection per query from a shared pgxpool
+	// of up to Connections connections instead of each holding their own.
+	// Requires churn_rate 0.
+	PoolMode bool `json:"pool_mode"`
+
+	// Fraction (0-1) of inserts handed to a reader on another connection,
+	// which polls until the row is visible (cross-connection read-your-writes).
+	// Only readers holding persistent connections take probes.
+	VisibilityCheckRate float64 `json:"visibility_check_rate"`
+
+	// Extra connections opened and held idle (a SELECT 1 keepalive every 30s)
+	// alongside the working ones, to test how the pooler copes with hoarding
+	IdleConnections int `json:"idle_connections"`
+
+	// Mixed operations: when any weight is set, every connection is a mixed
+	// worker picking a read, insert, or update per query by these weights
+	// instead of being a dedicated reader or writer. Reads draw on read_qps;
+	// inserts and updates on write_qps. Requires workers holding their own
+	// connections, and reads then go to the primary, so it can't be combined
+	// with read endpoints or visibility checks.
+	OperationWeights OperationWeights `json:"operation_weights"`
+}
+
+// Read strategies
+const (
+	ReadStrategyUniform = "uniform"
+	ReadStrategyRecent  = "recent"
+)
+
+// Write modes
+const (
+	WriteModeInsert = "insert"
+	WriteModeUpsert = "upsert"
+)
+
+// Read modes
+const (
+	ReadModePoint  = "point"
+	ReadModeRange  = "range"
+	ReadModeInList = "in_list"
+)
+
+// DefaultReadRatio is the read_ratio the server starts with, so total_qps on
+// its own gives a read-heavy mix rather than only writes
+const DefaultReadRatio = 0.8
+
+// defaultReadRangeSize is how many rows a range read fetches when
+// read_range_size is unset
+const defaultReadRangeSize = 100
+
+// defaultReadInListSize is how many IDs an in_list read looks up when
+// read_in_list_size is unset
+const defaultReadInListSize = 100
+
+// Validate checks that the configuration values are within sensible ranges
+func (c Config) Validate() error {
+	if c.Connections < 0 || c.IdleConnections < 0 {
+		return fmt.Errorf("connections and idle_connections must not be negative")
+	}
+	if c.ReadQPS < 0 || c.WriteQPS < 0 || c.TotalQPS < 0 {
+		return fmt.Errorf("read_qps, write_qps, and total_qps must not be negative")
+	}
+	if c.MaxConcurrentReads < 0 || c.MaxConcurrentWrites < 0 {
+		return fmt.Errorf("max_concurrent_reads and max_concurrent_writes must not be negative")
+	}
+	if c.RampSeconds < 0 {
+		return fmt.Errorf("ramp_seconds must not be negative")
+	}
+	if c.WarmupQueries < 0 || c.WarmupSeconds < 0 {
+		return fmt.Errorf("warmup_queries and warmup_seconds must not be negative")
+	}
+	if c.ReadRatio < 0 || c.ReadRatio > 1 {
+		return fmt.Errorf("read_ratio must be between 0 and 1")
+	}
+	// Zero connections spawns no workers, so any QPS would silently go nowhere
+	if readQPS, writeQPS := c.EffectiveQPS(); c.Connections == 0 && (readQPS > 0 || writeQPS > 0) {
+		return fmt.Errorf("connections must be at least 1 when read_qps or write_qps is set")
+	}
+	if c.ExplainSampleRate < 0 || c.ExplainSampleRate > 1 {
+		return fmt.Errorf("explain_sample_rate must be between 0 and 1")
+	}
+	if c.WriteRetries < 0 || c.TransientRetries < 0 {
+		return fmt.Errorf("write_retries and transient_retries must not be negative")
+	}
+	if c.StatementsPerTransaction < 0 {
+		return fmt.Errorf("statements_per_transaction must not be negative")
+	}
+	if c.WriteBatchSize < 0 || c.WriteBatchSize > maxWriteBatchSize {
+		return fmt.Errorf("write_batch_size must be between 0 and %d", maxWriteBatchSize)
+	}
+	if c.WriteBatchSize > 1 && c.StatementsPerTransaction > 1 {
+		return fmt.Errorf("write_batch_size cannot be combined with statements_per_transaction")
+	}
+	switch c.WriteMode {
+	case "", WriteModeInsert:
+	case WriteModeUpsert:
+		if c.WriteBatchSize > 1 || c.StatementsPerTransaction > 1 {
+			return fmt.Errorf("write_mode %q cannot be combined with write_batch_size or statements_per_transaction", WriteModeUpsert)
+		}
+	default:
+		return fmt.Errorf("write_mode must be %q or %q", WriteModeInsert, WriteModeUpsert)
+	}
+	if c.UpsertConflictRate < 0 || c.UpsertConflictRate > 1 {
+		return fmt.Errorf("upsert_conflict_rate must be between 0 and 1")
+	}
+	if c.ErrorInjectionRate < 0 || c.ErrorInjectionRate > 1 {
+		return fmt.Errorf("error_injection_rate must be between 0 and 1")
+	}
+	if c.QueryTimeoutMs < 0 || c.DrainTimeoutMs < 0 {
+		return fmt.Errorf("query_timeout_ms and drain_timeout_ms must not be negative")
+	}
+	if c.InjectLatencyMs < 0 || c.InjectLatencyJitterMs < 0 {
+		return fmt.Errorf("inject_latency_ms and inject_latency_jitter_ms must not be negative")
+	}
+	switch c.ReadStrategy {
+	case "", ReadStrategyUniform, ReadStrategyRecent:
+	default:
+		return fmt.Errorf("read_strategy must be %q or %q", ReadStrategyUniform, ReadStrategyRecent)
+	}
+	if c.RecentWindow < 0 {
+		return fmt.Errorf("recent_window must not be negative")
+	}
+	switch c.ReadMode {
+	case "", ReadModePoint, ReadModeRange, ReadModeInList:
+	default:
+		return fmt.Errorf("read_mode must be %q, %q, or %q", ReadModePoint, ReadModeRange, ReadModeInList)
+	}
+	if c.ReadRangeSize < 0 || c.ReadInListSize < 0 {
+		return fmt.Errorf("read_range_size and read_in_list_size must not be negative")
+	}
+	if c.VisibilityCheckRate < 0 || c.VisibilityCheckRate > 1 {
+		return fmt.Errorf("visibility_check_rate must be between 0 and 1")
+	}
+	if c.MaxConnLifetimeSec < 0 {
+		return fmt.Errorf("max_conn_lifetime_sec must not be negative")
+	}
+	if c.MaxConnLifetimeJitterPct < 0 || c.MaxConnLifetimeJitterPct > 100 {
+		return fmt.Errorf("max_conn_lifetime_jitter_pct must be between 0 and 100")
+	}
+	if c.ChaosIntervalSec < 0 {
+		return fmt.Errorf("chaos_interval_sec must not be negative")
+	}
+	if c.ChaosFraction < 0 || c.ChaosFraction > 1 {
+		return fmt.Errorf("chaos_fraction must be between 0 and 1")
+	}
+	if c.AppInstances < 0 || c.ConnectionsPerInstance < 0 {
+		return fmt.Errorf("app_instances and connections_per_instance must not be negative")
+	}
+	if c.PoolMode {
+		switch {
+		case c.ChurnRate > 0:
+			return fmt.Errorf("pool_mode reuses connections and cannot be combined with churn_rate")
+		case c.MaxConnLifetimeSec > 0:
+			return fmt.Errorf("pool_mode cannot be combined with max_conn_lifetime_sec")
+		case c.PerQueryConnect:
+			return fmt.Errorf("pool_mode cannot be combined with per_query_connect")
+		case c.AppInstances > 0:
+			return fmt.Errorf("pool_mode cannot be combined with app_instances")
+		}
+	}
+	if w := c.OperationWeights; w.Read < 0 || w.Insert < 0 || w.Update < 0 {
+		return fmt.Errorf("operation_weights must not be negative")
+	}
+	if c.OperationWeights.Enabled() && (c.PerQueryConnect || c.PoolMode || c.AppInstances > 0) {
+		return fmt.Errorf("operation_weights cannot be combined with per_query_connect, pool_mode, or app_instances")
+	}
+	if c.OperationWeights.Enabled() && c.VisibilityCheckRate > 0 {
+		// Mixed workers read on the connection they write on
+		return fmt.Errorf("visibility_check_rate needs dedicated readers and cannot be combined with operation_weights")
+	}
+	if c.AppInstances > 0 {
+		if c.ConnectionsPerInstance < 1 {
+			return fmt.Errorf("connections_per_instance must be at least 1 when app_instances is set")
+		}
+		if c.PerQueryConnect {
+			return fmt.Errorf("per_query_connect cannot be combined with app_instances")
+		}
+	}
+	return nil
 }
 
+// EffectiveQPS returns the read and write rates to run at: the explicit
+// read_qps/write_qps if either is set, otherwise total_qps split by read_ratio
+func (c Config) EffectiveQPS() (readQPS, writeQPS int) {
+	if c.ReadQPS > 0 || c.WriteQPS > 0 || c.TotalQPS == 0 {
+		return c.ReadQPS, c.WriteQPS
+	}
+	readQPS = int(float64(c.TotalQPS)*c.ReadRatio + 0.5)
+	return readQPS, c.TotalQPS - readQPS
+}
+
+// DBConnections is how many connections cfg opens to the database: the
+// pooled ones in app instance mode, otherwise one per worker, plus idle ones
+func (c Config) DBConnections() int {
+	connections := c.Connections
+	if c.AppInstances > 0 {
+		connections = c.AppInstances * c.ConnectionsPerInstance
+	}
+	return connections + c.IdleConnections
+}
+
+// maxWriteBatchSize keeps a batched insert within Postgres' 65535 bind
+// parameters (two per row)
+const maxWriteBatchSize = 32767
+
+// pausePollInterval is how often paused workers check whether to resume
+const pausePollInterval = 50 * time.Millisecond
+
 // Controller manages the load generation workers
 type Controller struct {
-	mu sync.RWMutex
+	mu     sync.RWMutex
+	stopMu sync.Mutex // Serializes Stop, which drains without holding mu
 
-	running bool
-	config  Config
+	running  bool
+	stopping bool // Stop is draining; config changes wait for the next Start
+	config   Config
+	initial  Config // Set by SetConfig; the base a replacing config update starts from
 
 	// Rate limiters (shared across workers)
 	readLimiter  *rate.Limiter
 	writeLimiter *rate.Limiter
 
+	// Configured rates, and the ramp-up progress (0-1, as float64 bits)
+	// scaling them into the limiters; progress is 1 when not ramping
+	readTarget   atomic.Int64
+	writeTarget  atomic.Int64
+	rampProgress atomic.Uint64
+
+	// Per-operation pause flags checked by workers (connections stay open)
+	readsPaused  atomic.Bool
+	writesPaused atomic.Bool
+
 	// Dependencies
 	connMgr   *db.ConnectionManager
 	collector *metrics.Collector
+	queries   Queries
 	maxUserID int64
+	ids       *idCache // Recently inserted IDs, fed by writers
+	probes    chan visibilityProbe
+	endpoints []Endpoint // Weighted read endpoints (empty = primary only)
+
+	// Run timestamps (stoppedAt is zero while running)
+	startedAt time.Time
+	stoppedAt time.Time
+
+	// Ends the collector's warmup once warmup_seconds pass (nil = no warmup)
+	warmupTimer *time.Timer
 
-	// Worker management
-	ctx    context.Context
-	cancel context.CancelFunc
-	wg     sync.WaitGroup
+	// Start refuses to run past this time (zero = no limit)
+	deadline time.Time
+
+	// Cancels the warmup_queries warmup a Start is running without mu, so
+	// Stop can abort it (nil = not warming up)
+	cancelWarmup context.CancelFunc
+
+	// Worker management. Workers wait and loop on issueCtx, which a drain
+	// cancels so they exit once their in-flight query is done; the queries
+	// themselves run until ctx is cancelled.
+	ctx         context.Context
+	cancel      context.CancelFunc
+	issueCtx    context.Context
+	stopIssuing context.CancelFunc
+	wg          sync.WaitGroup
+	pools       []*appPool // App instance pools, drained once workers exit
+	shared      bool       // Workers borrow from the connection manager's pgxpool
+
+	// Running workers by kind, each individually cancellable so connection
+	// count changes can add or remove just the difference
+	setup        workerSetup
+	readWorkers  []workerRef
+	writeWorkers []workerRef
+	mixedWorkers []workerRef
+	idleWorkers  []workerRef
+	chaosHandles *handleSet // nil when chaos mode is off
+
+	// Worker liveness for stuck-worker detection
+	heartbeats  []*heartbeat
+	liveWorkers atomic.Int32
 }
 
 // NewController creates a new load controller
-func NewController(connMgr *db.ConnectionManager, collector *metrics.Collector, maxUserID int64) *Controller {
-	return &Controller{
+func NewController(connMgr *db.ConnectionManager, collector *metrics.Collector, maxUserID int64, tableName string) *Controller {
+	c := &Controller{
 		connMgr:      connMgr,
 		collector:    collector,
+		queries:      NewQueries(tableName),
+		ids:          newIDCache(),
+		probes:       make(chan visibilityProbe, visibilityProbeBuffer),
 		maxUserID:    maxUserID,
 		readLimiter:  rate.NewLimiter(rate.Limit(100), 100),
 		writeLimiter: rate.NewLimiter(rate.Limit(10), 10),
 	}
+	c.rampProgress.Store(math.Float64bits(1))
+	return c
 }
 
-// Start begins load generation with the current configuration
-func (c *Controller) Start() {
+// Start begins load generation with the current configuration. It fails
+// without starting if the configuration is invalid.
+func (c *Controller) Start() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.running {
-		return
+	if c.running || c.cancelWarmup != nil {
+		return nil
+	}
+	if !c.deadline.IsZero() && !time.Now().Before(c.deadline) {
+		return fmt.Errorf("max runtime reached at %s; load can no longer be started", c.deadline.Format(time.RFC3339))
+	}
+	if err := c.check(c.config); err != nil {
+		return err
 	}
 
-	c.ctx, c.cancel = context.WithCancel(context.Background())
+	// Warm up without holding mu so requests and Stop aren't blocked; Stop
+	// cancels it, and then nothing starts
+	if n := c.config.WarmupQueries; n > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), warmupTimeout)
+		defer cancel()
+		c.cancelWarmup = cancel
+		endpoints := c.endpoints
+
+		c.mu.Unlock()
+		c.warmup(ctx, n, endpoints)
+		c.mu.Lock()
+
+		c.cancelWarmup = nil
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return nil
+		}
+	}
+	if c.config.WarmupSeconds > 0 {
+		c.collector.BeginWarmup()
+		c.warmupTimer = time.AfterFunc(time.Duration(c.config.WarmupSeconds)*time.Second, c.collector.EndWarmup)
+	}
+	c.startWorkers(time.Duration(c.config.RampSeconds) * time.Second)
 	c.running = true
+	c.startedAt = time.Now()
+	c.stoppedAt = time.Time{}
+	return nil
+}
+
+// Check validates cfg, and that it can send reads to the read endpoints if
+// any are set
+func (c *Controller) Check(cfg Config) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.check(cfg)
+}
+
+// check is Check for a caller holding c.mu
+func (c *Controller) check(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if len(c.endpoints) == 0 {
+		return nil
+	}
+	switch {
+	case cfg.OperationWeights.Enabled():
+		// Mixed workers read on the connection they write on, to the primary
+		return fmt.Errorf("operation_weights cannot be combined with read endpoints (READ_ENDPOINTS or READ_DATABASE_URL)")
+	case cfg.PoolMode || cfg.AppInstances > 0:
+		// Pooled readers borrow primary connections shared with writers
+		return fmt.Errorf("pool_mode and app_instances cannot be combined with read endpoints (READ_ENDPOINTS or READ_DATABASE_URL)")
+	}
+	return nil
+}
+
+// startWorkers spawns workers for the current configuration, ramping up
+// over ramp if non-zero (caller holds c.mu)
+func (c *Controller) startWorkers(ramp time.Duration) {
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.issueCtx, c.stopIssuing = context.WithCancel(c.ctx)
+	ctx := c.issueCtx
+
+	if ramp > 0 {
+		c.rampProgress.Store(0)
+		c.setLimits()
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.runRamp(ctx, ramp)
+		}()
+	}
+
+	// In app instance mode the database sees only the pooled connections
+	dbConnections := c.config.Connections
+	c.pools = nil
+	if c.config.AppInstances > 0 {
+		dbConnections = c.config.AppInstances * c.config.ConnectionsPerInstance
+	}
+
+	// Never hold more connections than configured, even mid-churn
+	c.connMgr.SetConnectionLimit(c.config.DBConnections())
 
 	// Calculate churn rate per connection
 	// If we have 1000 connections and want 100 churns/sec,
 	// each connection has a 0.1 probability of churning per second
 	var churnRate float64
-	if c.config.Connections > 0 && c.config.ChurnRate > 0 {
-		churnRate = float64(c.config.ChurnRate) / float64(c.config.Connections)
+	if dbConnections > 0 && c.config.ChurnRate > 0 {
+		churnRate = float64(c.config.ChurnRate) / float64(dbConnections)
 	}
 
-	// Split connections between readers and writers (80/20)
-	numReaders := (c.config.Connections * 80) / 100
-	if numReaders < 1 && c.config.Connections > 0 {
-		numReaders = 1
+	for i := 0; i < c.config.AppInstances; i++ {
+		c.pools = append(c.pools, newAppPool(c.connMgr, c.collector, c.config.ConnectionsPerInstance, newConnLifetime(churnRate, c.config)))
 	}
-	numWriters := c.config.Connections - numReaders
-	if numWriters < 0 {
-		numWriters = 0
+	c.shared = false
+	if c.config.PoolMode {
+		if err := c.connMgr.OpenPool(dbConnections); err != nil {
+			slog.Warn("Could not open connection pool, workers will hold their own connections", "error", err)
+		} else {
+			c.shared = true
+		}
 	}
+	c.heartbeats = nil
+	c.readWorkers, c.writeWorkers, c.mixedWorkers, c.idleWorkers = nil, nil, nil, nil
 
-	// Start read workers
-	for i := 0; i < numReaders; i++ {
+	// Handles on persistent connections, for the chaos loop to sever
+	c.chaosHandles = nil
+	if c.config.ChaosIntervalSec > 0 && c.config.ChaosFraction > 0 {
+		c.chaosHandles = &handleSet{}
+		interval := time.Duration(c.config.ChaosIntervalSec) * time.Second
+		handles, fraction := c.chaosHandles, c.config.ChaosFraction
 		c.wg.Add(1)
 		go func() {
 			defer c.wg.Done()
-			worker := NewReadWorker(c.connMgr, c.readLimiter, c.collector, c.maxUserID, churnRate)
-			worker.Run(c.ctx)
+			c.runChaos(ctx, handles, interval, fraction)
 		}()
 	}
 
-	// Start write workers
-	for i := 0; i < numWriters; i++ {
-		c.wg.Add(1)
-		go func() {
-			defer c.wg.Done()
-			worker := NewWriteWorker(c.connMgr, c.writeLimiter, c.collector, churnRate)
-			worker.Run(c.ctx)
-		}()
+	c.setup = workerSetup{
+		churnRate:  churnRate,
+		batchQuery: c.queries.withWriteBatch(c.config.WriteBatchSize).WriteBatch,
+
+		// Per-operation concurrency caps shared by all workers of each kind
+		readSlots:  newInflightLimiter(c.config.MaxConcurrentReads, c.collector.RecordReadConcurrencyWait),
+		writeSlots: newInflightLimiter(c.config.MaxConcurrentWrites, c.collector.RecordWriteConcurrencyWait),
 	}
+	c.resizeWorkers(ramp)
+}
+
+// newHeartbeat registers a heartbeat and live count for a worker about to be
+// spawned (caller holds c.mu)
+func (c *Controller) newHeartbeat() *heartbeat {
+	hb := &heartbeat{}
+	c.heartbeats = append(c.heartbeats, hb)
+	c.liveWorkers.Add(1)
+	return hb
+}
+
+// poolFor assigns the nth worker to the shared pool in pool mode or to an app
+// instance pool round-robin, or returns nil when workers hold their own
+// connections (caller holds c.mu)
+func (c *Controller) poolFor(n int) connPool {
+	if c.shared {
+		return sharedPool{connMgr: c.connMgr}
+	}
+	if len(c.pools) == 0 {
+		return nil
+	}
+	return c.pools[n%len(c.pools)]
 }
 
 // Stop gracefully stops all workers
 func (c *Controller) Stop() {
+	c.stopMu.Lock()
+	defer c.stopMu.Unlock()
+
+	c.mu.Lock()
+	running, timeout := c.running, time.Duration(c.config.DrainTimeoutMs)*time.Millisecond
+	if c.cancelWarmup != nil {
+		c.cancelWarmup()
+	}
+	c.stopping = running
+	c.mu.Unlock()
+	if !running {
+		return
+	}
+
+	// Let in-flight queries finish. The drain doesn't hold mu, so status and
+	// config requests aren't blocked by it; stopping keeps config updates
+	// from resizing or restarting the workers meanwhile.
+	c.drain(timeout)
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.stopWorkers()
+	c.stopping = false
+	c.running = false
+	c.stoppedAt = time.Now()
 
-	if !c.running {
+	// Stopped before warmup ended: nothing was recorded, so resume recording
+	if c.warmupTimer != nil {
+		if c.warmupTimer.Stop() {
+			c.collector.EndWarmup()
+		}
+		c.warmupTimer = nil
+	}
+}
+
+// drain stops workers issuing queries, then waits up to timeout for them to
+// exit as their in-flight queries finish (caller holds stopMu, not mu)
+func (c *Controller) drain(timeout time.Duration) {
+	if timeout <= 0 {
 		return
 	}
+	c.stopIssuing()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		slog.Info("Drain timed out; cancelling in-flight queries", "in_flight", c.inFlight(), "timeout", timeout)
+	}
+}
 
+// stopWorkers cancels all workers and waits for them to exit (caller holds c.mu)
+func (c *Controller) stopWorkers() {
 	c.cancel()
 	c.wg.Wait()
-	c.running = false
+	for _, pool := range c.pools {
+		pool.drain()
+	}
+	if c.shared {
+		c.connMgr.ClosePool()
+	}
 }
 
-// UpdateConfig updates the load configuration
+// UpdateConfig updates the load configuration. While Stop is draining, the
+// new configuration only takes effect on the next Start.
 func (c *Controller) UpdateConfig(cfg Config) {
 	c.mu.Lock()
 	oldConfig := c.config
 	c.config = cfg
 
-	// Update rate limiters immediately (burst = QPS for smooth rate)
-	c.readLimiter.SetLimit(rate.Limit(cfg.ReadQPS))
-	c.readLimiter.SetBurst(max(cfg.ReadQPS, 1))
-	c.writeLimiter.SetLimit(rate.Limit(cfg.WriteQPS))
-	c.writeLimiter.SetBurst(max(cfg.WriteQPS, 1))
+	// Update rate limiters immediately
+	c.applyRates(cfg)
 
-	// If running and connection count or churn changed, restart workers
-	needsRestart := c.running && (oldConfig.Connections != cfg.Connections || oldConfig.ChurnRate != cfg.ChurnRate)
+	// If running and anything besides the rate limits changed, resize or
+	// restart workers
+	if c.running && !c.stopping && workerConfigChanged(oldConfig, cfg) {
+		if resizable(oldConfig, cfg) {
+			c.connMgr.SetConnectionLimit(cfg.DBConnections())
+			c.resizeWorkers(0)
+		} else {
+			c.stopWorkers()
+			c.startWorkers(0)
+		}
+	}
 	c.mu.Unlock()
+}
 
-	if needsRestart {
-		c.Stop()
-		c.Start()
+// workerConfigChanged reports whether a config change affects settings that
+// workers capture at spawn time (everything except the shared rate limits
+// and the warmup and ramp, which only apply on Start)
+func workerConfigChanged(oldConfig, newConfig Config) bool {
+	oldConfig.ReadQPS, oldConfig.WriteQPS, oldConfig.TotalQPS, oldConfig.ReadRatio = 0, 0, 0, 0
+	newConfig.ReadQPS, newConfig.WriteQPS, newConfig.TotalQPS, newConfig.ReadRatio = 0, 0, 0, 0
+	oldConfig.WarmupQueries, newConfig.WarmupQueries = 0, 0
+	oldConfig.WarmupSeconds, newConfig.WarmupSeconds = 0, 0
+	oldConfig.RampSeconds, newConfig.RampSeconds = 0, 0
+	oldConfig.DrainTimeoutMs, newConfig.DrainTimeoutMs = 0, 0
+	return oldConfig != newConfig
+}
+
+// applyRates sets the shared rate limiters from cfg, scaled down while ramping
+func (c *Controller) applyRates(cfg Config) {
+	readQPS, writeQPS := cfg.EffectiveQPS()
+	c.readTarget.Store(int64(readQPS))
+	c.writeTarget.Store(int64(writeQPS))
+	c.setLimits()
+}
+
+// SetPaused pauses or resumes reads and/or writes without closing connections.
+// A drain on Stop doesn't use the pause flags, so resuming mid-drain issues
+// nothing new; the flags carry over to the next Start.
+func (c *Controller) SetPaused(reads, writes, paused bool) {
+	if reads {
+		c.readsPaused.Store(paused)
+	}
+	if writes {
+		c.writesPaused.Store(paused)
 	}
 }
 
+// Paused reports whether reads and writes are currently paused
+func (c *Controller) Paused() (reads, writes bool) {
+	return c.readsPaused.Load(), c.writesPaused.Load()
+}
+
+// RunTimes returns when the current or most recent run started and stopped.
+// stopped is zero while running; both are zero if no run has started.
+func (c *Controller) RunTimes() (started, stopped time.Time) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.startedAt, c.stoppedAt
+}
+
+// CachedIDs returns how many recently inserted IDs the controller holds for
+// reads and an estimate of their memory footprint in bytes
+func (c *Controller) CachedIDs() (count int, bytes int64) {
+	return c.ids.Len(), c.ids.SizeBytes()
+}
+
+// Collector returns the metrics collector this controller's workers record to
+func (c *Controller) Collector() *metrics.Collector {
+	return c.collector
+}
+
+// Queries returns the SQL issued by this controller's workers
+func (c *Controller) Queries() Queries {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.queries.withWriteBatch(c.config.WriteBatchSize)
+}
+
 // GetConfig returns the current configuration
 func (c *Controller) GetConfig() Config {
 	c.mu.RLock()
@@ -152,14 +777,27 @@ func (c *Controller) IsRunning() bool {
 	return c.running
 }
 
+// SetDeadline makes Start refuse once t has passed, so a stopped run can't
+// be restarted past the process's max runtime
+func (c *Controller) SetDeadline(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+}
+
 // SetConfig sets the initial configuration without restarting
 func (c *Controller) SetConfig(cfg Config) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	c.config = cfg
-	c.readLimiter.SetLimit(rate.Limit(cfg.ReadQPS))
-	c.readLimiter.SetBurst(max(cfg.ReadQPS, 1))
-	c.writeLimiter.SetLimit(rate.Limit(cfg.WriteQPS))
-	c.writeLimiter.SetBurst(max(cfg.WriteQPS, 1))
+	c.initial = cfg
+	c.applyRates(cfg)
+}
+
+// InitialConfig returns the configuration set by SetConfig
+func (c *Controller) InitialConfig() Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.initial
 }
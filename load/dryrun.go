@@ -0,0 +1,70 @@
+package load
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"supafirehose/db"
+)
+
+// DryRun checks that each query the current configuration would issue
+// succeeds, once, without starting workers or recording metrics. Writes are
+// rolled back.
+func (c *Controller) DryRun(ctx context.Context) db.DryRunResult {
+	c.mu.RLock()
+	cfg := c.config
+	queries := c.queries.withWriteBatch(cfg.WriteBatchSize)
+	maxUserID := c.maxUserID
+	endpoints := c.endpoints
+	c.mu.RUnlock()
+
+	randNum := rand.Int63()
+	username := fmt.Sprintf("user_%d", randNum)
+	email := fmt.Sprintf("user_%d@example.com", randNum)
+
+	reads := []db.QueryCheck{
+		{Name: "read", Query: queries.Read, Args: []any{rand.Int63n(maxUserID) + 1}},
+	}
+	if cfg.ReadMode == ReadModeRange {
+		reads = append(reads, db.QueryCheck{Name: "read_range", Query: queries.ReadRange, Args: []any{1, 0}})
+	}
+	if cfg.ReadMode == ReadModeInList {
+		reads = append(reads, db.QueryCheck{Name: "read_in_list", Query: queries.ReadIn, Args: []any{[]int64{rand.Int63n(maxUserID) + 1}}})
+	}
+
+	checks := []db.QueryCheck{{Name: "write", Query: queries.Write, Args: []any{username, email}}}
+	if cfg.WriteMode == WriteModeUpsert {
+		checks[0] = db.QueryCheck{Name: "upsert", Query: queries.Upsert, Args: []any{nil, username, email}}
+	}
+	if len(endpoints) == 0 {
+		checks = append(reads, checks...)
+	}
+	if queries.WriteBatch != "" {
+		checks = append(checks, db.QueryCheck{Name: "write_batch", Query: queries.WriteBatch, Args: batchArgs(cfg.WriteBatchSize)})
+	}
+	if cfg.OperationWeights.Update > 0 {
+		checks = append(checks, db.QueryCheck{Name: "update", Query: queries.Update, Args: []any{rand.Int63n(maxUserID) + 1, email}})
+	}
+
+	result := c.connMgr.DryRun(ctx, "", checks)
+
+	// Reads go to the read endpoints instead of the primary
+	for _, ep := range endpoints {
+		mergeEndpoint(&result, ep.Name, c.connMgr.DryRun(ctx, ep.URL, reads))
+	}
+	return result
+}
+
+// mergeEndpoint folds the dry run of one read endpoint into result, naming
+// its checks after the endpoint
+func mergeEndpoint(result *db.DryRunResult, endpoint string, r db.DryRunResult) {
+	result.OK = result.OK && r.OK
+	if r.Error != "" {
+		result.Queries = append(result.Queries, db.QueryCheckResult{Name: "connect@" + endpoint, Error: r.Error})
+	}
+	for _, q := range r.Queries {
+		q.Name += "@" + endpoint
+		result.Queries = append(result.Queries, q)
+	}
+}
@@ -0,0 +1,39 @@
+package load
+
+// Endpoint is a named read endpoint that a share of read workers connect to
+type Endpoint struct {
+	Name   string
+	URL    string
+	Weight int
+}
+
+// SetReadEndpoints spreads read workers across endpoints in proportion to
+// their weights, taking effect on the next start. Writers always use the
+// primary connection string. Configs whose reads can't reach an endpoint
+// (pool mode, app instances, mixed workers) are rejected while any are set.
+func (c *Controller) SetReadEndpoints(endpoints []Endpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.endpoints = endpoints
+}
+
+// endpointFor assigns the nth read worker to an endpoint by weighted
+// round-robin, or returns nil if no endpoints are configured (caller holds c.mu)
+func (c *Controller) endpointFor(n int) *Endpoint {
+	total := 0
+	for _, ep := range c.endpoints {
+		total += ep.Weight
+	}
+	if total == 0 {
+		return nil
+	}
+
+	slot := n % total
+	for i := range c.endpoints {
+		if slot < c.endpoints[i].Weight {
+			return &c.endpoints[i]
+		}
+		slot -= c.endpoints[i].Weight
+	}
+	return nil
+}
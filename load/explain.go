@@ -0,0 +1,42 @@
+package load
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// explainPrefix is prepended to queries sampled under EXPLAIN ANALYZE
+const explainPrefix = "EXPLAIN (ANALYZE, FORMAT JSON) "
+
+// explainResult is the subset of EXPLAIN (FORMAT JSON) output we care about
+type explainResult struct {
+	PlanningTime  float64 `json:"Planning Time"`
+	ExecutionTime float64 `json:"Execution Time"`
+}
+
+// explainAnalyze runs the query under EXPLAIN (ANALYZE, FORMAT JSON) and
+// returns the server-reported planning and execution times. It runs in the
+// same exec mode as the workload's own queries: as a statement prepared on
+// conn if prepared, otherwise parsed fresh.
+func explainAnalyze(ctx context.Context, conn *pgx.Conn, prepared bool, sql string, args ...any) (planning, execution time.Duration, err error) {
+	var raw []byte
+	if err := conn.QueryRow(ctx, explainPrefix+sql, execArgs(prepared, args...)...).Scan(&raw); err != nil {
+		return 0, 0, err
+	}
+
+	var results []explainResult
+	if err := json.Unmarshal(raw, &results); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse explain output: %w", err)
+	}
+	if len(results) == 0 {
+		return 0, 0, fmt.Errorf("empty explain output")
+	}
+
+	planning = time.Duration(results[0].PlanningTime * float64(time.Millisecond))
+	execution = time.Duration(results[0].ExecutionTime * float64(time.Millisecond))
+	return planning, execution, nil
+}
@@ -0,0 +1,115 @@
+package load
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// heartbeat records when a worker's in-flight query or connect started, so
+// workers stuck on a hung connection or dial can be spotted. A nil heartbeat
+// is a no-op.
+type heartbeat struct {
+	busySince  atomic.Int64 // Unix nanoseconds; 0 while idle
+	connecting atomic.Bool  // Busy opening or borrowing a connection rather than in a query
+}
+
+func (h *heartbeat) begin() {
+	if h != nil {
+		h.connecting.Store(false)
+		h.busySince.Store(time.Now().UnixNano())
+	}
+}
+
+// beginConnect marks the worker busy opening or borrowing a connection.
+// It counts toward stalled workers but not queries in flight, so a drain
+// doesn't wait on workers queued for a connection.
+func (h *heartbeat) beginConnect() {
+	if h != nil {
+		h.connecting.Store(true)
+		h.busySince.Store(time.Now().UnixNano())
+	}
+}
+
+func (h *heartbeat) end() {
+	if h != nil {
+		h.busySince.Store(0)
+		h.connecting.Store(false)
+	}
+}
+
+// hold is called right after begin, just before issuing a query. It fails
+// once ctx is done, and while paused is set it clears the in-flight mark and
+// waits for resume or ctx, so a query that got past its worker's pause check
+// (or sat in the rate limiter or concurrency cap) isn't issued during a pause
+// or a drain on Stop.
+func (h *heartbeat) hold(ctx context.Context, paused *atomic.Bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for paused.Load() {
+		h.end()
+		if err := sleepContext(ctx, pausePollInterval); err != nil {
+			return err
+		}
+		h.begin()
+	}
+	return nil
+}
+
+// inFlight counts workers currently inside a query
+func (c *Controller) inFlight() int {
+	c.mu.RLock()
+	heartbeats := c.heartbeats
+	c.mu.RUnlock()
+
+	n := 0
+	for _, hb := range heartbeats {
+		if hb.busySince.Load() != 0 && !hb.connecting.Load() {
+			n++
+		}
+	}
+	return n
+}
+
+// WorkerHealth summarizes worker liveness for stuck-worker detection
+type WorkerHealth struct {
+	Expected       int     `json:"expected"`          // Workers spawned for the current run
+	Live           int     `json:"live"`              // Worker goroutines still running
+	Busy           int     `json:"busy"`              // Workers currently inside a query or connect
+	Stalled        int     `json:"stalled"`           // Busy for longer than the threshold
+	LongestBusySec float64 `json:"longest_busy_sec"`  // Longest in-flight query or connect right now
+	ThresholdSec   float64 `json:"stalled_after_sec"` // Threshold used for Stalled
+}
+
+// WorkerHealth reports how many workers are live, busy, and stuck in a
+// query or connect for longer than stalledAfter
+func (c *Controller) WorkerHealth(stalledAfter time.Duration) WorkerHealth {
+	c.mu.RLock()
+	heartbeats := c.heartbeats
+	running := c.running
+	c.mu.RUnlock()
+
+	health := WorkerHealth{
+		Live:         int(c.liveWorkers.Load()),
+		ThresholdSec: stalledAfter.Seconds(),
+	}
+	if running {
+		health.Expected = len(heartbeats)
+	}
+
+	now := time.Now().UnixNano()
+	for _, hb := range heartbeats {
+		since := hb.busySince.Load()
+		if since == 0 {
+			continue
+		}
+		busy := time.Duration(now - since)
+		health.Busy++
+		if busy > stalledAfter {
+			health.Stalled++
+		}
+		health.LongestBusySec = max(health.LongestBusySec, busy.Seconds())
+	}
+	return health
+}
@@ -0,0 +1,69 @@
+package load
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// idCacheSize is the number of recently inserted IDs retained for reads
+const idCacheSize = 10000
+
+// idCache is a ring buffer of recently inserted IDs shared by workers
+type idCache struct {
+	mu   sync.Mutex
+	ids  []int64
+	next int // ring position of the next insert once full
+}
+
+func newIDCache() *idCache {
+	return &idCache{ids: make([]int64, 0, idCacheSize)}
+}
+
+// Add records a newly inserted ID, evicting the oldest when full
+func (c *idCache) Add(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.ids) < idCacheSize {
+		c.ids = append(c.ids, id)
+		return
+	}
+	c.ids[c.next] = id
+	c.next = (c.next + 1) % idCacheSize
+}
+
+// Recent picks an ID from the newest window entries, biased toward the most
+// recent (exponential with mean window/4). ok is false if the cache is empty.
+func (c *idCache) Recent(window int) (id int64, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := len(c.ids)
+	if n == 0 {
+		return 0, false
+	}
+	window = min(max(window, 1), n)
+
+	age := min(int(rand.ExpFloat64()*float64(window)/4), window-1)
+
+	// Newest entry sits just before next (or at the end before wraparound)
+	newest := n - 1
+	if n == idCacheSize {
+		newest = (c.next - 1 + n) % n
+	}
+	return c.ids[(newest-age+n)%n], true
+}
+
+// Len returns the number of cached IDs
+func (c *idCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.ids)
+}
+
+// SizeBytes estimates the memory held by the cache (the ring is allocated up front)
+func (c *idCache) SizeBytes() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return int64(cap(c.ids)) * 8
+}
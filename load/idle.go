@@ -0,0 +1,63 @@
+package load
+
+import (
+	"context"
+	"time"
+
+	"supafirehose/db"
+	"supafirehose/metrics"
+)
+
+// idleKeepalive is how often idle workers touch their connection so poolers
+// and firewalls don't reap it
+const idleKeepalive = 30 * time.Second
+
+// IdleWorker holds a connection open without doing real work, sending only a
+// periodic SELECT 1, to model apps hoarding connections. Its keepalives are
+// not recorded as reads.
+type IdleWorker struct {
+	connMgr   *db.ConnectionManager
+	collector *metrics.Collector
+	handle    *connHandle // Lets the chaos loop sever the connection
+}
+
+// NewIdleWorker creates a new idle worker
+func NewIdleWorker(connMgr *db.ConnectionManager, collector *metrics.Collector) *IdleWorker {
+	return &IdleWorker{connMgr: connMgr, collector: collector}
+}
+
+// Run holds a connection until ctx is done, reconnecting if it breaks
+func (w *IdleWorker) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		conn, err := w.connMgr.Connect(ctx)
+		if err != nil {
+			// Failures are logged by the connection manager
+			if sleepContext(ctx, 100*time.Millisecond) != nil {
+				return
+			}
+			continue
+		}
+
+		if recovered := w.handle.set(conn); recovered > 0 {
+			w.collector.RecordChaosReconnect(recovered)
+		}
+
+		connectedAt := time.Now()
+		for sleepContext(ctx, idleKeepalive) == nil {
+			if _, err := conn.Exec(ctx, "SELECT 1"); err != nil {
+				break
+			}
+		}
+		w.handle.clear()
+
+		conn.Close(context.Background())
+		w.connMgr.Release()
+		w.collector.RecordConnectionLifetime(time.Since(connectedAt))
+	}
+}
@@ -0,0 +1,134 @@
+package load
+
+import (
+	"context"
+	"time"
+
+	"supafirehose/db"
+	"supafirehose/metrics"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// connPool lends workers a connection per query
+type connPool interface {
+	// get borrows a connection, waiting for one if the pool is exhausted
+	get(ctx context.Context) (*pooledConn, error)
+	// put returns a connection; failed marks the query on it as failed
+	put(pc *pooledConn, failed bool)
+}
+
+// appPool models one application instance's client-side connection pool:
+// the instance's workers share up to size persistent connections, waiting
+// for one to free up when all are busy
+type appPool struct {
+	connMgr   *db.ConnectionManager
+	collector *metrics.Collector
+	lifetime  connLifetime // When to churn each connection
+
+	slots chan struct{}    // One token per connection checked out or being opened
+	idle  chan *pooledConn // Open connections not currently in use
+}
+
+// pooledConn is a connection owned by a connPool
+type pooledConn struct {
+	conn        *pgx.Conn
+	connectedAt time.Time
+	churnAfter  time.Time     // Zero means never churn
+	lease       *pgxpool.Conn // Set when borrowed from the shared pgxpool
+}
+
+func newAppPool(connMgr *db.ConnectionManager, collector *metrics.Collector, size int, lifetime connLifetime) *appPool {
+	return &appPool{
+		connMgr:   connMgr,
+		collector: collector,
+		lifetime:  lifetime,
+		slots:     make(chan struct{}, size),
+		idle:      make(chan *pooledConn, size),
+	}
+}
+
+// get checks out an idle connection, opening a new one if the pool is below
+// its size, or waits for one to be returned
+func (p *appPool) get(ctx context.Context) (*pooledConn, error) {
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		// Every connection is checked out; report the wait
+		p.connMgr.AddWaiting(1)
+		select {
+		case p.slots <- struct{}{}:
+			p.connMgr.AddWaiting(-1)
+		case <-ctx.Done():
+			p.connMgr.AddWaiting(-1)
+			return nil, ctx.Err()
+		}
+	}
+
+	select {
+	case pc := <-p.idle:
+		p.connMgr.AddIdle(-1)
+		return pc, nil
+	default:
+	}
+
+	conn, err := p.connMgr.Connect(ctx)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+
+	pc := &pooledConn{conn: conn, connectedAt: time.Now()}
+	pc.churnAfter = p.lifetime.deadline(pc.connectedAt)
+	return pc, nil
+}
+
+// put returns a connection to the pool, closing it instead if the query on
+// it failed or its churn lifetime has passed
+func (p *appPool) put(pc *pooledConn, failed bool) {
+	if failed || (!pc.churnAfter.IsZero() && time.Now().After(pc.churnAfter)) {
+		p.close(pc)
+	} else {
+		p.connMgr.AddIdle(1)
+		p.idle <- pc
+	}
+	<-p.slots
+}
+
+// drain closes all idle connections (call after every worker has exited)
+func (p *appPool) drain() {
+	for {
+		select {
+		case pc := <-p.idle:
+			p.connMgr.AddIdle(-1)
+			p.close(pc)
+		default:
+			return
+		}
+	}
+}
+
+func (p *appPool) close(pc *pooledConn) {
+	pc.conn.Close(context.Background())
+	p.connMgr.Release()
+	p.collector.RecordConnectionLifetime(time.Since(pc.connectedAt))
+}
+
+// sharedPool lends connections from the connection manager's pgxpool (pool
+// mode). pgxpool itself discards connections left broken by a failed query.
+type sharedPool struct {
+	connMgr *db.ConnectionManager
+}
+
+func (p sharedPool) get(ctx context.Context) (*pooledConn, error) {
+	lease, err := p.connMgr.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &pooledConn{conn: lease.Conn(), lease: lease}, nil
+}
+
+func (p sharedPool) put(pc *pooledConn, failed bool) {
+	pc.lease.Release()
+}
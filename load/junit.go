@@ -0,0 +1,57 @@
+package load
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// junitSuite is a JUnit XML testsuite, the format CI result viewers read
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// WriteJUnit writes the search as a JUnit XML suite with one max_qps test
+// case, failing when MaxQPS is below minQPS (or no rate was sustained if
+// minQPS is 0). The steps are listed in the case's output.
+func (r SearchResult) WriteJUnit(w io.Writer, minQPS int) error {
+	var steps strings.Builder
+	for _, step := range r.Steps {
+		fmt.Fprintln(&steps, step.String())
+	}
+	c := junitCase{Name: "max_qps", ClassName: "supafirehose.find_max_qps", SystemOut: steps.String()}
+	if minQPS = max(minQPS, 1); r.MaxQPS < minQPS {
+		c.Failure = &junitFailure{Message: fmt.Sprintf("max sustainable QPS %d is below the minimum of %d", r.MaxQPS, minQPS)}
+	}
+
+	suite := junitSuite{Name: "find-max-qps", Tests: 1, Cases: []junitCase{c}}
+	if c.Failure != nil {
+		suite.Failures = 1
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,41 @@
+package load
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// latencyInjector simulates network distance between client and database
+type latencyInjector struct {
+	base   time.Duration
+	jitter time.Duration
+}
+
+func newLatencyInjector(cfg Config) latencyInjector {
+	return latencyInjector{
+		base:   time.Duration(cfg.InjectLatencyMs) * time.Millisecond,
+		jitter: time.Duration(cfg.InjectLatencyJitterMs) * time.Millisecond,
+	}
+}
+
+// delay samples a delay uniformly from [base-jitter, base+jitter], never negative
+func (l latencyInjector) delay() time.Duration {
+	d := l.base
+	if l.jitter > 0 {
+		d += time.Duration(rand.Int63n(int64(2*l.jitter)+1)) - l.jitter
+	}
+	return max(d, 0)
+}
+
+// wait sleeps for a sampled delay and returns how long was injected
+func (l latencyInjector) wait(ctx context.Context) (time.Duration, error) {
+	if l.base == 0 && l.jitter == 0 {
+		return 0, nil
+	}
+	d := l.delay()
+	if err := sleepContext(ctx, d); err != nil {
+		return 0, err
+	}
+	return d, nil
+}
@@ -0,0 +1,49 @@
+package load
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Bounds on a single churned connection's lifetime
+const (
+	minConnLifetime      = 100 * time.Millisecond
+	maxChurnConnLifetime = 60 * time.Second
+)
+
+// connLifetime decides how long a persistent connection lives before it is
+// closed and reopened
+type connLifetime struct {
+	churnRate float64       // Probability of churning a connection per second
+	fixed     time.Duration // Lifetime overriding churnRate (0 = use churnRate)
+	jitter    float64       // Fraction (0-1) fixed may vary by either way
+}
+
+// newConnLifetime combines the per-connection churn rate with cfg's max
+// connection lifetime, which wins when set
+func newConnLifetime(churnRate float64, cfg Config) connLifetime {
+	return connLifetime{
+		churnRate: churnRate,
+		fixed:     time.Duration(cfg.MaxConnLifetimeSec) * time.Second,
+		jitter:    float64(cfg.MaxConnLifetimeJitterPct) / 100,
+	}
+}
+
+// deadline returns when a connection opened at connectedAt should be closed,
+// or the zero time to keep it until it breaks
+func (l connLifetime) deadline(connectedAt time.Time) time.Time {
+	var lifetime time.Duration
+	switch {
+	case l.fixed > 0:
+		// Spread uniformly over fixed ± jitter, like a pooler's server_lifetime
+		lifetime = time.Duration(float64(l.fixed) * (1 + l.jitter*(2*rand.Float64()-1)))
+	case l.churnRate > 0:
+		// Exponential lifetimes: a churnRate of 0.1 averages 10 seconds
+		avgLifetime := time.Duration(float64(time.Second) / l.churnRate)
+		lifetime = time.Duration(rand.ExpFloat64() * float64(avgLifetime))
+		lifetime = min(lifetime, maxChurnConnLifetime)
+	default:
+		return time.Time{}
+	}
+	return connectedAt.Add(max(lifetime, minConnLifetime))
+}
@@ -0,0 +1,274 @@
+package load
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Operations a mixed worker picks between
+const (
+	opRead = iota
+	opInsert
+	opUpdate
+)
+
+// OperationWeights sets how often mixed workers pick each operation. Weights
+// are relative: 70/20/10 and 7/2/1 give the same mix.
+type OperationWeights struct {
+	Read   int `json:"read"`
+	Insert int `json:"insert"`
+	Update int `json:"update"`
+}
+
+// Enabled reports whether any weight is set (mixed workers replace the
+// reader/writer split)
+func (w OperationWeights) Enabled() bool {
+	return w.Read > 0 || w.Insert > 0 || w.Update > 0
+}
+
+// MixedWorker holds one connection and picks a read, insert, or update for
+// each query by the configured weights, like an app connection that does a
+// bit of everything. The queries themselves are run by a ReadWorker and
+// WriteWorker, so each operation is rate limited, capped, and recorded as
+// usual: reads against read_qps and inserts and updates against write_qps.
+type MixedWorker struct {
+	reader      *ReadWorker
+	writer      *WriteWorker
+	weights     OperationWeights
+	updateQuery string
+	handle      *connHandle // Lets the chaos loop sever the connection
+
+	// Reissues an operation that failed on a broken connection
+	retry   transientRetry
+	retryOp int
+}
+
+// NewMixedWorker creates a mixed worker from a reader and writer sharing its
+// connection
+func NewMixedWorker(reader *ReadWorker, writer *WriteWorker, weights OperationWeights, updateQuery string) *MixedWorker {
+	return &MixedWorker{
+		reader:      reader,
+		writer:      writer,
+		weights:     weights,
+		updateQuery: updateQuery,
+		retry:       transientRetry{max: writer.retry.max},
+	}
+}
+
+// Run starts the mixed worker loop with its own connection
+func (m *MixedWorker) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		m.writer.heartbeat.beginConnect()
+		conn, err := m.writer.connMgr.Connect(ctx)
+		m.writer.heartbeat.end()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			m.writer.collector.RecordWrite(0, err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		if recovered := m.handle.set(conn); recovered > 0 {
+			m.writer.collector.RecordChaosReconnect(recovered)
+		}
+
+		connectedAt := time.Now()
+		m.runWithConnection(ctx, conn)
+		m.handle.clear()
+
+		conn.Close(context.Background())
+		m.writer.connMgr.Release()
+		m.writer.collector.RecordConnectionLifetime(time.Since(connectedAt))
+	}
+}
+
+func (m *MixedWorker) runWithConnection(ctx context.Context, conn *pgx.Conn) {
+	if m.writer.prepared {
+		queries := append(m.reader.statements(), m.writer.statements()...)
+		if m.weights.Update > 0 {
+			queries = append(queries, m.updateQuery)
+		}
+		if err := prepareAll(ctx, conn, queries...); err != nil {
+			if ctx.Err() == nil {
+				m.writer.collector.RecordWrite(0, err)
+				sleepContext(ctx, prepareFailedBackoff)
+			}
+			return
+		}
+	}
+
+	churnAfter := m.writer.lifetime.deadline(time.Now())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		if !churnAfter.IsZero() && time.Now().After(churnAfter) {
+			return
+		}
+
+		// Reissue an operation that failed on the previous connection
+		if start, ok := m.retry.pending(); ok {
+			if m.retry.wait(ctx) != nil {
+				return
+			}
+			if err := m.run(ctx, conn, m.retryOp, start); err != nil {
+				return
+			}
+			continue
+		}
+
+		op, ok := m.pick()
+		if !ok {
+			// Every weighted operation is paused or has no rate
+			if err := sleepContext(ctx, pausePollInterval); err != nil {
+				return
+			}
+			continue
+		}
+
+		// Abandon the connection if it broke to force a reconnect
+		if err := m.execute(ctx, conn, op); err != nil {
+			return
+		}
+	}
+}
+
+// pick rolls an operation by weight, skipping operations that are paused or
+// whose rate is 0 so one idle side doesn't strand the worker
+func (m *MixedWorker) pick() (op int, ok bool) {
+	weights := [...]int{
+		opRead:   m.weights.Read,
+		opInsert: m.weights.Insert,
+		opUpdate: m.weights.Update,
+	}
+	if m.reader.paused.Load() || m.reader.limiter.Limit() == 0 {
+		weights[opRead] = 0
+	}
+	if m.writer.paused.Load() || m.writer.limiter.Limit() == 0 {
+		weights[opInsert], weights[opUpdate] = 0, 0
+	}
+
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	if total == 0 {
+		return 0, false
+	}
+
+	n := rand.Intn(total)
+	for op, w := range weights {
+		if n < w {
+			return op, true
+		}
+		n -= w
+	}
+	return 0, false
+}
+
+// execute waits for the operation's rate limiter, then runs and records it
+func (m *MixedWorker) execute(ctx context.Context, conn *pgx.Conn, op int) error {
+	limiter := m.writer.limiter
+	if op == opRead {
+		limiter = m.reader.limiter
+	}
+	if err := limiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	// Simulate network distance; the delay counts toward measured latency
+	start := time.Now()
+	injected, err := m.writer.latency.wait(ctx)
+	if err != nil {
+		return err
+	}
+
+	if op == opRead {
+		m.writer.collector.RecordReadInjected(injected)
+	} else {
+		m.writer.collector.RecordWriteInjected(injected)
+	}
+	return m.run(ctx, conn, op, start)
+}
+
+// run issues the operation and records it with latency measured from start,
+// unless it failed on a broken connection and will be retried on the next
+func (m *MixedWorker) run(ctx context.Context, conn *pgx.Conn, op int, start time.Time) error {
+	var err error
+	slotWait := &m.writer.slotWait
+	switch op {
+	case opRead:
+		err = m.reader.read(ctx, conn)
+		slotWait = &m.reader.slotWait
+	case opInsert:
+		err = m.writer.write(ctx, conn)
+	case opUpdate:
+		err = m.update(ctx, conn)
+	}
+	latency := time.Since(start) - *slotWait
+
+	// Don't record context cancellation as an error (expected during shutdown)
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+	if m.retry.postpone(err, start, op != opRead) {
+		m.retryOp = op
+		if op == opRead {
+			m.writer.collector.RecordReadRetry()
+		} else {
+			m.writer.collector.RecordWriteRetry()
+		}
+		return err
+	}
+	if op == opRead {
+		m.reader.record(latency, err)
+	} else {
+		m.writer.record(latency, err)
+	}
+	return connErr(conn, err)
+}
+
+// update rewrites the email of a random existing user
+func (m *MixedWorker) update(ctx context.Context, conn *pgx.Conn) (err error) {
+	w := m.writer
+	w.slotWait, err = w.inflight.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer w.inflight.release()
+
+	w.heartbeat.begin()
+	defer w.heartbeat.end()
+	if err := w.heartbeat.hold(ctx, w.paused); err != nil {
+		return err
+	}
+
+	ctx, detach := issueContext(ctx, w.stop)
+	defer detach()
+	ctx, cancel := w.timeout.bound(ctx)
+	defer cancel()
+	defer func() { err = w.timeout.label(ctx, err) }()
+
+	w.explained = false
+	id := rand.Int63n(m.reader.maxID) + 1
+	email := fmt.Sprintf("user_%d@example.com", rand.Int63())
+	if _, err = conn.Exec(ctx, m.updateQuery, execArgs(w.prepared, id, email)...); err == nil {
+		w.collector.RecordWriteBytes(valueBytes(id, email))
+	}
+	return err
+}
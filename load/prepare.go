@@ -0,0 +1,38 @@
+package load
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// prepareAll prepares each non-empty query on conn, named by its own SQL
+// text, so later queries with that text run the prepared statement without
+// being parsed again whatever the connection's query exec mode
+func prepareAll(ctx context.Context, conn *pgx.Conn, queries ...string) error {
+	for _, q := range queries {
+		if q == "" {
+			continue
+		}
+		if _, err := conn.Prepare(ctx, q, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execArgs returns args for running a query on a connection, prefixed with
+// QueryExecModeExec unless the connection prepared its queries. That parses
+// the query on every execution instead of hitting pgx's statement cache, so
+// running without prepared statements is a true unprepared baseline.
+func execArgs(prepared bool, args ...any) []any {
+	if prepared {
+		return args
+	}
+	return append([]any{pgx.QueryExecModeExec}, args...)
+}
+
+// prepareFailedBackoff is how long a worker waits before reconnecting after
+// its queries failed to prepare, so a missing table doesn't spin
+const prepareFailedBackoff = 100 * time.Millisecond
@@ -0,0 +1,81 @@
+package load
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Queries holds the SQL issued by workers, bound to a specific table
+type Queries struct {
+	Table     string
+	Read      string
+	ReadRange string // Used when read_mode is "range"
+	ReadIn    string // Used when read_mode is "in_list"
+	Write     string
+	Upsert    string // Used when write_mode is "upsert"
+	Update    string // Used by mixed workers (operation_weights)
+
+	// Multi-row insert used when writes are batched (empty otherwise)
+	WriteBatch string
+}
+
+// NewQueries builds the worker SQL for the given (unquoted) table name
+func NewQueries(table string) Queries {
+	quoted := pgx.Identifier{table}.Sanitize()
+
+	// A NULL id takes the next value from the id sequence, so only upserts
+	// given an existing id conflict
+	sequence := "pg_get_serial_sequence('" + strings.ReplaceAll(quoted, "'", "''") + "', 'id')"
+	upsert := "INSERT INTO " + quoted + " (id, username, email) VALUES (COALESCE($1::bigint, nextval(" + sequence + ")), $2, $3)" +
+		" ON CONFLICT (id) DO UPDATE SET username = EXCLUDED.username, email = EXCLUDED.email RETURNING id"
+
+	return Queries{
+		Table:     table,
+		Read:      "SELECT id, username, email, created_at FROM " + quoted + " WHERE id = $1",
+		ReadRange: "SELECT id, username, email, created_at FROM " + quoted + " ORDER BY id LIMIT $1 OFFSET $2",
+		ReadIn:    "SELECT id, username, email, created_at FROM " + quoted + " WHERE id = ANY($1::bigint[])",
+		Write:     "INSERT INTO " + quoted + " (username, email) VALUES ($1, $2) RETURNING id",
+		Upsert:    upsert,
+		Update:    "UPDATE " + quoted + " SET email = $2 WHERE id = $1",
+	}
+}
+
+// All returns every query text workers may issue, including EXPLAIN variants
+func (q Queries) All() []string {
+	all := []string{q.Read, q.ReadRange, q.ReadIn, q.Write, q.Upsert, q.Update, explainPrefix + q.Read, explainPrefix + q.ReadRange, explainPrefix + q.ReadIn, explainPrefix + q.Write, explainPrefix + q.Upsert}
+	if q.WriteBatch != "" {
+		all = append(all, q.WriteBatch)
+	}
+	return all
+}
+
+// insert returns the single-row write workers issue under cfg
+func (q Queries) insert(cfg Config) string {
+	if cfg.WriteMode == WriteModeUpsert {
+		return q.Upsert
+	}
+	return q.Write
+}
+
+// withWriteBatch returns q with WriteBatch set to a multi-row insert of n
+// rows, or cleared when n <= 1
+func (q Queries) withWriteBatch(n int) Queries {
+	q.WriteBatch = ""
+	if n <= 1 {
+		return q
+	}
+
+	var sb strings.Builder
+	sb.WriteString("INSERT INTO " + pgx.Identifier{q.Table}.Sanitize() + " (username, email) VALUES ")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "($%d, $%d)", 2*i+1, 2*i+2)
+	}
+	sb.WriteString(" RETURNING id")
+	q.WriteBatch = sb.String()
+	return q
+}
@@ -0,0 +1,78 @@
+package load
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rampTick is how often the rate limits are raised during a ramp
+const rampTick = 100 * time.Millisecond
+
+// setLimits sets the shared rate limiters to the target rates scaled by the
+// ramp progress (burst = QPS for smooth rate)
+func (c *Controller) setLimits() {
+	scale := math.Float64frombits(c.rampProgress.Load())
+	readQPS := rampedQPS(c.readTarget.Load(), scale)
+	writeQPS := rampedQPS(c.writeTarget.Load(), scale)
+	c.readLimiter.SetLimit(rate.Limit(readQPS))
+	c.readLimiter.SetBurst(max(readQPS, 1))
+	c.writeLimiter.SetLimit(rate.Limit(writeQPS))
+	c.writeLimiter.SetBurst(max(writeQPS, 1))
+}
+
+// Ramping reports whether the rate limits are still below the target rates
+// because a ramp is in progress
+func (c *Controller) Ramping() bool {
+	return math.Float64frombits(c.rampProgress.Load()) < 1
+}
+
+// runRamp raises the rate limits linearly from zero to the target rates over
+// duration. Rate changes during the ramp move the target. The limits are at
+// full rate once it returns, including when ctx is cancelled early.
+func (c *Controller) runRamp(ctx context.Context, duration time.Duration) {
+	defer func() {
+		c.rampProgress.Store(math.Float64bits(1))
+		c.setLimits()
+	}()
+
+	ticker := time.NewTicker(rampTick)
+	defer ticker.Stop()
+
+	start := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		progress := float64(time.Since(start)) / float64(duration)
+		if progress >= 1 {
+			return
+		}
+		c.rampProgress.Store(math.Float64bits(progress))
+		c.setLimits()
+	}
+}
+
+// rampedQPS scales target by the ramp progress, keeping non-zero rates at
+// least 1 QPS: a zero limit reserves tokens infinitely far out, leaving
+// waiting workers stuck after the limit rises
+func rampedQPS(target int64, scale float64) int {
+	if target == 0 {
+		return 0
+	}
+	return max(int(float64(target)*scale), 1)
+}
+
+// rampDelay staggers the ith of n workers across the ramp so connections are
+// opened gradually
+func rampDelay(ramp time.Duration, i, n int) time.Duration {
+	if ramp <= 0 || n == 0 {
+		return 0
+	}
+	return ramp * time.Duration(i) / time.Duration(n)
+}
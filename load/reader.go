@@ -3,6 +3,7 @@ package load
 import (
 	"context"
 	"math/rand"
+	"sync/atomic"
 	"time"
 
 	"supafirehose/db"
@@ -14,22 +15,73 @@ import (
 
 // ReadWorker executes read queries against the database
 type ReadWorker struct {
-	connMgr     *db.ConnectionManager
-	limiter     *rate.Limiter
-	collector   *metrics.Collector
-	maxID       int64
-	churnRate   float64 // Probability of churning connection per second
+	connMgr         *db.ConnectionManager
+	limiter         *rate.Limiter
+	paused          *atomic.Bool // Skip issuing reads while set
+	collector       *metrics.Collector
+	query           string
+	rangeQuery      string // Fetches rangeSize rows from an offset
+	rangeSize       int    // Rows per range read (0 = point reads by ID)
+	inListQuery     string // Fetches inListSize rows by an array of IDs
+	inListSize      int    // IDs per in-list read (0 = point reads by ID)
+	maxID           int64
+	ids             *idCache
+	recentWindow    int          // Pick from this many recent inserts (0 = uniform reads)
+	lifetime        connLifetime // When to churn the persistent connection
+	perQueryConnect bool         // Open a new connection for every query
+	latency         latencyInjector
+	explainRate     float64              // Fraction of reads run under EXPLAIN ANALYZE
+	pool            connPool             // Borrow a connection per query (nil = own connection)
+	probes          chan visibilityProbe // Fresh IDs from writers to check for visibility
+	endpoint        *Endpoint            // Read endpoint to connect to (nil = primary)
+	heartbeat       *heartbeat           // Marks in-flight queries for stuck-worker detection
+	handle          *connHandle          // Lets the chaos loop sever the persistent connection
+	inflight        *inflightLimiter     // Caps concurrent reads across workers (nil = unlimited)
+	slotWait        time.Duration        // How long the last read waited for an inflight slot
+	explained       bool                 // The last read ran under EXPLAIN ANALYZE
+	timeout         queryTimeout
+	stop            context.Context // Cancels an in-flight read; outlives ctx through a drain (nil = ctx)
+	retry           transientRetry  // Reissues reads that failed on a broken connection
+	prepared        bool            // Prepare queries once per persistent connection
 }
 
 // NewReadWorker creates a new read worker
-func NewReadWorker(connMgr *db.ConnectionManager, limiter *rate.Limiter, collector *metrics.Collector, maxID int64, churnRate float64) *ReadWorker {
-	return &ReadWorker{
-		connMgr:   connMgr,
-		limiter:   limiter,
-		collector: collector,
-		maxID:     maxID,
-		churnRate: churnRate,
+func NewReadWorker(connMgr *db.ConnectionManager, limiter *rate.Limiter, paused *atomic.Bool, collector *metrics.Collector, query string, maxID int64, ids *idCache, churnRate float64, cfg Config) *ReadWorker {
+	w := &ReadWorker{
+		connMgr:         connMgr,
+		limiter:         limiter,
+		paused:          paused,
+		collector:       collector,
+		query:           query,
+		maxID:           maxID,
+		ids:             ids,
+		lifetime:        newConnLifetime(churnRate, cfg),
+		perQueryConnect: cfg.PerQueryConnect,
+		latency:         newLatencyInjector(cfg),
+		explainRate:     cfg.ExplainSampleRate,
+		timeout:         queryTimeout(time.Duration(cfg.QueryTimeoutMs) * time.Millisecond),
+		retry:           transientRetry{max: cfg.TransientRetries},
+		prepared:        cfg.PreparedStatements,
 	}
+	if cfg.ReadMode == ReadModeRange {
+		w.rangeSize = cfg.ReadRangeSize
+		if w.rangeSize == 0 {
+			w.rangeSize = defaultReadRangeSize
+		}
+	}
+	if cfg.ReadMode == ReadModeInList {
+		w.inListSize = cfg.ReadInListSize
+		if w.inListSize == 0 {
+			w.inListSize = defaultReadInListSize
+		}
+	}
+	if cfg.ReadStrategy == ReadStrategyRecent {
+		w.recentWindow = cfg.RecentWindow
+		if w.recentWindow == 0 {
+			w.recentWindow = 1000
+		}
+	}
+	return w
 }
 
 // User represents a row from the users table
@@ -42,6 +94,15 @@ type User struct {
 
 // Run starts the read worker loop with its own connection
 func (w *ReadWorker) Run(ctx context.Context) {
+	if w.pool != nil {
+		w.runPooled(ctx)
+		return
+	}
+	if w.perQueryConnect {
+		w.runPerQuery(ctx)
+		return
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -50,86 +111,374 @@ func (w *ReadWorker) Run(ctx context.Context) {
 		}
 
 		// Create a new connection
-		conn, err := w.connMgr.Connect(ctx)
+		w.heartbeat.beginConnect()
+		conn, err := w.connect(ctx)
+		w.heartbeat.end()
 		if err != nil {
 			// Don't record context cancellation as error (expected during shutdown)
 			if ctx.Err() != nil {
 				return
 			}
 			// Record connection error and backoff
-			w.collector.RecordRead(0, err)
+			w.record(0, err)
 			time.Sleep(100 * time.Millisecond)
 			continue
 		}
 
+		if recovered := w.handle.set(conn); recovered > 0 {
+			w.collector.RecordChaosReconnect(recovered)
+		}
+
 		// Run queries on this connection until churn or context done
+		connectedAt := time.Now()
 		w.runWithConnection(ctx, conn)
+		w.handle.clear()
 
 		// Close connection (use background context to ensure clean close)
 		conn.Close(context.Background())
 		w.connMgr.Release()
+		w.collector.RecordConnectionLifetime(time.Since(connectedAt))
 	}
 }
 
-func (w *ReadWorker) runWithConnection(ctx context.Context, conn *pgx.Conn) {
-	// Calculate when to churn this connection
-	// If churnRate is 0.1 (10%), average connection lifetime is 10 seconds
-	var churnAfter time.Time
-	if w.churnRate > 0 {
-		// Random lifetime based on churn rate (exponential distribution)
-		avgLifetime := time.Duration(float64(time.Second) / w.churnRate)
-		lifetime := time.Duration(rand.ExpFloat64() * float64(avgLifetime))
-		// Cap lifetime to reasonable bounds
-		if lifetime < 100*time.Millisecond {
-			lifetime = 100 * time.Millisecond
+// runPerQuery opens a fresh connection for every query and closes it right
+// after, measuring connect+query+close as the unit latency
+func (w *ReadWorker) runPerQuery(ctx context.Context) {
+	for {
+		if w.paused.Load() {
+			if err := sleepContext(ctx, pausePollInterval); err != nil {
+				return
+			}
+			continue
+		}
+
+		if err := w.limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		start := time.Now()
+		injected, err := w.latency.wait(ctx)
+		if err != nil {
+			return
+		}
+		w.collector.RecordReadInjected(injected)
+
+		w.heartbeat.beginConnect()
+		conn, err := w.connect(ctx)
+		w.heartbeat.end()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			w.record(time.Since(start), err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		connectedAt := time.Now()
+		err = w.read(ctx, conn)
+		conn.Close(context.Background())
+		w.connMgr.Release()
+		w.collector.RecordConnectionLifetime(time.Since(connectedAt))
+		latency := time.Since(start) - w.slotWait
+
+		if err != nil && ctx.Err() != nil {
+			return
 		}
-		if lifetime > 60*time.Second {
-			lifetime = 60 * time.Second
+		w.record(latency, err)
+	}
+}
+
+// runPooled borrows a connection from the worker's app instance pool for each
+// query; time spent waiting for a free connection counts toward latency
+func (w *ReadWorker) runPooled(ctx context.Context) {
+	for {
+		if w.paused.Load() {
+			if err := sleepContext(ctx, pausePollInterval); err != nil {
+				return
+			}
+			continue
+		}
+
+		if err := w.limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		start := time.Now()
+		injected, err := w.latency.wait(ctx)
+		if err != nil {
+			return
+		}
+		w.collector.RecordReadInjected(injected)
+
+		w.heartbeat.beginConnect()
+		pc, err := w.pool.get(ctx)
+		w.heartbeat.end()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			w.record(time.Since(start), err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		err = w.read(ctx, pc.conn)
+		latency := time.Since(start) - w.slotWait
+		w.pool.put(pc, err != nil)
+
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+		w.record(latency, err)
+	}
+}
+
+func (w *ReadWorker) runWithConnection(ctx context.Context, conn *pgx.Conn) {
+	if w.prepared {
+		if err := prepareAll(ctx, conn, w.statements()...); err != nil {
+			if ctx.Err() == nil {
+				w.record(0, err)
+				sleepContext(ctx, prepareFailedBackoff)
+			}
+			return
 		}
-		churnAfter = time.Now().Add(lifetime)
 	}
 
+	// Calculate when to churn this connection
+	churnAfter := w.lifetime.deadline(time.Now())
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
 			// Check if it's time to churn
-			if w.churnRate > 0 && time.Now().After(churnAfter) {
+			if !churnAfter.IsZero() && time.Now().After(churnAfter) {
 				return // Exit to churn connection
 			}
 
+			// Reissue a read that failed on the previous connection
+			if start, ok := w.retry.pending(); ok {
+				if w.retry.wait(ctx) != nil {
+					return
+				}
+				if err := w.executeRead(ctx, conn, start); err != nil {
+					return
+				}
+				continue
+			}
+
+			// Hold the connection open but issue nothing while paused
+			if w.paused.Load() {
+				if err := sleepContext(ctx, pausePollInterval); err != nil {
+					return
+				}
+				continue
+			}
+
+			// Check a writer's fresh insert is visible on this connection
+			// (outside the rate limit and read latency)
+			if err := w.checkVisibility(ctx, conn); err != nil {
+				return
+			}
+
 			// Wait for rate limiter
 			if err := w.limiter.Wait(ctx); err != nil {
 				return
 			}
 
-			// Execute query; abandon connection on error to force reconnect
-			if err := w.executeRead(ctx, conn); err != nil {
+			// Simulate network distance; the delay counts toward measured latency
+			start := time.Now()
+			injected, err := w.latency.wait(ctx)
+			if err != nil {
+				return
+			}
+			w.collector.RecordReadInjected(injected)
+
+			// Execute query; abandon the connection if it broke to force a reconnect
+			if err := w.executeRead(ctx, conn, start); err != nil {
 				return
 			}
 		}
 	}
 }
 
-func (w *ReadWorker) executeRead(ctx context.Context, conn *pgx.Conn) error {
-	start := time.Now()
-
-	// Random ID within the known range
-	id := rand.Int63n(w.maxID) + 1
-
-	var user User
-	err := conn.QueryRow(ctx,
-		"SELECT id, username, email, created_at FROM users WHERE id = $1",
-		id,
-	).Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt)
-
-	latency := time.Since(start)
+func (w *ReadWorker) executeRead(ctx context.Context, conn *pgx.Conn, start time.Time) error {
+	err := w.read(ctx, conn)
+	latency := time.Since(start) - w.slotWait
 
 	// Don't record context cancellation as an error (expected during shutdown)
 	if err != nil && ctx.Err() != nil {
 		return err
 	}
+	if w.retry.postpone(err, start, false) {
+		w.collector.RecordReadRetry()
+		return err
+	}
+	w.record(latency, err)
+	return connErr(conn, err)
+}
+
+// connect opens a connection to the worker's read endpoint, or the primary
+func (w *ReadWorker) connect(ctx context.Context) (*pgx.Conn, error) {
+	if w.endpoint != nil {
+		return w.connMgr.ConnectTo(ctx, w.endpoint.URL)
+	}
+	return w.connMgr.Connect(ctx)
+}
+
+// record records a read overall and against the worker's endpoint, if any.
+// A read run under EXPLAIN ANALYZE is only counted, keeping its latency out
+// of the histograms.
+func (w *ReadWorker) record(latency time.Duration, err error) {
+	if w.explained {
+		w.explained = false
+		w.collector.RecordExplainedRead(err)
+		return
+	}
 	w.collector.RecordRead(latency, err)
+	if w.endpoint != nil {
+		w.collector.RecordEndpointRead(w.endpoint.Name, latency, err)
+	}
+}
+
+// pickID chooses the ID to read: biased toward recent inserts when configured,
+// otherwise uniformly random within the known range. It records which path
+// was taken, since random guesses may miss once rows are deleted or the
+// range is sparse.
+func (w *ReadWorker) pickID() int64 {
+	if w.recentWindow > 0 {
+		if id, ok := w.ids.Recent(w.recentWindow); ok {
+			w.collector.RecordReadID(true)
+			return id
+		}
+	}
+	w.collector.RecordReadID(false)
+	return rand.Int63n(w.maxID) + 1
+}
+
+// read issues a single read query on conn without recording its latency
+func (w *ReadWorker) read(ctx context.Context, conn *pgx.Conn) (err error) {
+	// The wait is reported as a concurrency wait, not read latency
+	w.slotWait, err = w.inflight.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer w.inflight.release()
+
+	w.heartbeat.begin()
+	defer w.heartbeat.end()
+	if err := w.heartbeat.hold(ctx, w.paused); err != nil {
+		return err
+	}
+
+	ctx, detach := issueContext(ctx, w.stop)
+	defer detach()
+	ctx, cancel := w.timeout.bound(ctx)
+	defer cancel()
+	defer func() { err = w.timeout.label(ctx, err) }()
+
+	w.explained = w.explainRate > 0 && rand.Float64() < w.explainRate
+	if w.rangeSize > 0 {
+		return w.readRange(ctx, conn)
+	}
+	if w.inListSize > 0 {
+		return w.readInList(ctx, conn)
+	}
+
+	id := w.pickID()
+
+	// Sample a fraction of reads under EXPLAIN ANALYZE to split planning from execution
+	if w.explained {
+		planning, execution, err := explainAnalyze(ctx, conn, w.prepared, w.query, id)
+		if err == nil {
+			w.collector.RecordReadPlan(planning, execution)
+		}
+		return err
+	}
+
+	var user User
+	err = conn.QueryRow(ctx, w.query, execArgs(w.prepared, id)...).
+		Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt)
+	if err == nil {
+		w.collector.RecordReadBytes(valueBytes(user.ID, user.Username, user.Email, user.CreatedAt))
+	}
 	return err
 }
+
+// statements lists the queries this worker issues, for preparing
+func (w *ReadWorker) statements() []string {
+	queries := []string{w.query}
+	switch {
+	case w.rangeSize > 0:
+		queries = append(queries, w.rangeQuery)
+	case w.inListSize > 0:
+		queries = append(queries, w.inListQuery)
+	}
+	if w.explainRate > 0 {
+		queries = append(queries, explainPrefix+queries[len(queries)-1])
+	}
+	return queries
+}
+
+// readRange fetches rangeSize rows from a random offset within the known ID
+// range, discarding them as they arrive, and records how many came back
+func (w *ReadWorker) readRange(ctx context.Context, conn *pgx.Conn) error {
+	offset := rand.Int63n(max(w.maxID-int64(w.rangeSize), 1))
+
+	if w.explained {
+		planning, execution, err := explainAnalyze(ctx, conn, w.prepared, w.rangeQuery, w.rangeSize, offset)
+		if err == nil {
+			w.collector.RecordReadPlan(planning, execution)
+		}
+		return err
+	}
+
+	rows, err := conn.Query(ctx, w.rangeQuery, execArgs(w.prepared, w.rangeSize, offset)...)
+	if err != nil {
+		return err
+	}
+	return w.drain(rows)
+}
+
+// readInList looks up inListSize IDs picked by the read strategy in a single
+// query, discarding the rows as they arrive, and records how many came back
+func (w *ReadWorker) readInList(ctx context.Context, conn *pgx.Conn) error {
+	ids := make([]int64, w.inListSize)
+	for i := range ids {
+		ids[i] = w.pickID()
+	}
+
+	if w.explained {
+		planning, execution, err := explainAnalyze(ctx, conn, w.prepared, w.inListQuery, ids)
+		if err == nil {
+			w.collector.RecordReadPlan(planning, execution)
+		}
+		return err
+	}
+
+	rows, err := conn.Query(ctx, w.inListQuery, execArgs(w.prepared, ids)...)
+	if err != nil {
+		return err
+	}
+	return w.drain(rows)
+}
+
+// drain reads rows to the end without decoding them, recording how many rows
+// and bytes came back
+func (w *ReadWorker) drain(rows pgx.Rows) error {
+	n, size := 0, 0
+	for rows.Next() {
+		n++
+		for _, v := range rows.RawValues() {
+			size += len(v)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	w.collector.RecordReadRows(n)
+	w.collector.RecordReadBytes(size)
+	return nil
+}
@@ -0,0 +1,127 @@
+package load
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	retryBaseBackoff = 5 * time.Millisecond
+	retryMaxBackoff  = 500 * time.Millisecond
+)
+
+// isRetryableWriteError reports whether err is a serialization failure (40001)
+// or deadlock (40P01), which applications typically retry
+func isRetryableWriteError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
+
+// retryBackoff returns an exponential backoff with full jitter for the given attempt
+func retryBackoff(attempt int) time.Duration {
+	backoff := min(retryBaseBackoff<<min(attempt, 10), retryMaxBackoff)
+	return time.Duration(rand.Int63n(int64(backoff)) + 1)
+}
+
+// sleepContext sleeps for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isTransientError reports whether err means the connection broke under the
+// query (reset, closed, or the server shutting the session down) rather than
+// the query itself failing, so it may succeed on a fresh connection. Query
+// timeouts and errors such as constraint violations are not transient.
+func isTransientError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Class 08 is connection exceptions; 57P01-57P03 are admin shutdown,
+		// crash shutdown, and cannot connect now
+		return strings.HasPrefix(pgErr.Code, "08") ||
+			pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03"
+	}
+	return pgconn.SafeToRetry(err) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// connErr returns err if it left conn unusable, so the worker should
+// reconnect, and nil if the query failed but conn can take the next one
+// (constraint violations, injected errors, and other server-reported errors)
+func connErr(conn *pgx.Conn, err error) error {
+	if err == nil || !conn.IsClosed() && !isTransientError(err) {
+		return nil
+	}
+	return err
+}
+
+// transientRetry carries a query that failed transiently over to the
+// worker's next connection, where it is retried before being recorded. Its
+// latency runs from the first attempt, so it includes the reconnect.
+type transientRetry struct {
+	max      int       // Retries per query (0 = record failures immediately)
+	attempts int       // Retries used by the pending query
+	start    time.Time // When the pending query was first issued (zero = none)
+}
+
+// postpone reports whether a query issued at start that failed with err
+// should be retried on a fresh connection instead of recorded, and if so
+// holds it as pending. A write that broke after being sent may already have
+// committed, so writes are only retried if they never reached the server.
+func (r *transientRetry) postpone(err error, start time.Time, write bool) bool {
+	retryable := isTransientError(err)
+	if write {
+		retryable = pgconn.SafeToRetry(err)
+	}
+	if err == nil || r.attempts >= r.max || !retryable {
+		r.done()
+		return false
+	}
+	if r.attempts == 0 {
+		r.start = start
+	}
+	r.attempts++
+	return true
+}
+
+// pending returns when the query awaiting a retry was first issued
+func (r *transientRetry) pending() (start time.Time, ok bool) {
+	return r.start, r.attempts > 0
+}
+
+// wait sleeps out the pending query's backoff before it is reissued, giving
+// a server that dropped the connection time to recover
+func (r *transientRetry) wait(ctx context.Context) error {
+	return sleepContext(ctx, retryBackoff(r.attempts-1))
+}
+
+// done clears the pending query once it has been recorded
+func (r *transientRetry) done() {
+	r.attempts = 0
+	r.start = time.Time{}
+}
@@ -0,0 +1,144 @@
+package load
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// SearchOptions controls FindMaxQPS
+type SearchOptions struct {
+	StartQPS     int           // First total QPS tried
+	MaxQPS       int           // Never target more than this total QPS
+	Hold         time.Duration // How long each step is measured
+	MinAchieved  float64       // Fraction of target QPS that must be achieved (e.g. 0.95)
+	MaxErrorRate float64       // Highest error rate a step may have and still pass
+}
+
+// SearchStep is one measured step of a throughput search
+type SearchStep struct {
+	TargetQPS   int     `json:"target_qps"`
+	AchievedQPS float64 `json:"achieved_qps"`
+	ErrorRate   float64 `json:"error_rate"`
+	OK          bool    `json:"ok"`
+}
+
+// SearchResult is the outcome of FindMaxQPS
+type SearchResult struct {
+	MaxQPS int          `json:"max_qps"` // Highest target that kept up (0 if none did)
+	Steps  []SearchStep `json:"steps"`
+}
+
+// searchPrecision stops the binary search once the bounds are this close
+const searchPrecision = 0.05
+
+// FindMaxQPS searches for the highest total QPS the target sustains: it
+// doubles the target from StartQPS until a step falls behind or errors too
+// much, then binary-searches between the last passing and first failing
+// rates. Reads and writes keep the configured ratio. report is called after
+// each step. Load is stopped when the search ends.
+func (c *Controller) FindMaxQPS(ctx context.Context, opts SearchOptions, report func(SearchStep)) (SearchResult, error) {
+	base := c.GetConfig()
+	readShare := 0.8
+	if readQPS, writeQPS := base.EffectiveQPS(); readQPS+writeQPS > 0 {
+		readShare = float64(readQPS) / float64(readQPS+writeQPS)
+	}
+
+	if err := c.Start(); err != nil {
+		return SearchResult{}, err
+	}
+	defer c.Stop()
+
+	var result SearchResult
+	step := func(target int) (bool, error) {
+		cfg := base
+		cfg.ReadQPS = int(float64(target)*readShare + 0.5)
+		cfg.WriteQPS = target - cfg.ReadQPS
+		c.UpdateConfig(cfg)
+
+		s, err := c.measure(ctx, target, opts)
+		if err != nil {
+			return false, err
+		}
+		result.Steps = append(result.Steps, s)
+		if report != nil {
+			report(s)
+		}
+		if s.OK {
+			result.MaxQPS = max(result.MaxQPS, target)
+		}
+		return s.OK, nil
+	}
+
+	// Grow geometrically until a step fails or the ceiling is reached
+	lo, hi := 0, 0
+	for target := max(opts.StartQPS, 1); ; target *= 2 {
+		target = min(target, opts.MaxQPS)
+		ok, err := step(target)
+		if err != nil {
+			return result, err
+		}
+		if !ok {
+			hi = target
+			break
+		}
+		lo = target
+		if target == opts.MaxQPS {
+			return result, nil
+		}
+	}
+
+	// Narrow in on the boundary
+	for hi-lo > 1 && float64(hi-lo) > float64(lo)*searchPrecision {
+		mid := lo + (hi-lo)/2
+		ok, err := step(mid)
+		if err != nil {
+			return result, err
+		}
+		if ok {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return result, nil
+}
+
+// measure holds the current rate for opts.Hold and reports whether the
+// target kept up. A short settle period lets workers ramp before measuring.
+func (c *Controller) measure(ctx context.Context, target int, opts SearchOptions) (SearchStep, error) {
+	settle := opts.Hold / 5
+	if err := sleepContext(ctx, settle); err != nil {
+		return SearchStep{}, err
+	}
+	c.collector.Snapshot(settle, 0) // discard the ramp-up window
+
+	if err := sleepContext(ctx, opts.Hold); err != nil {
+		return SearchStep{}, err
+	}
+	snap := c.collector.Snapshot(opts.Hold, 0)
+
+	achieved := snap.Reads.QPS + snap.Writes.QPS
+	queries := achieved * opts.Hold.Seconds()
+	var errorRate float64
+	if queries > 0 {
+		errorRate = float64(snap.Reads.Errors+snap.Writes.Errors) / queries
+	}
+
+	s := SearchStep{
+		TargetQPS:   target,
+		AchievedQPS: achieved,
+		ErrorRate:   errorRate,
+	}
+	s.OK = achieved >= float64(target)*opts.MinAchieved && errorRate <= opts.MaxErrorRate
+	return s, nil
+}
+
+// String summarizes the step for logs
+func (s SearchStep) String() string {
+	verdict := "ok"
+	if !s.OK {
+		verdict = "FAIL"
+	}
+	return fmt.Sprintf("target=%d achieved=%.0f errors=%.2f%% %s", s.TargetQPS, s.AchievedQPS, s.ErrorRate*100, verdict)
+}
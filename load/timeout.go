@@ -0,0 +1,43 @@
+package load
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// queryTimeout bounds each read or write; zero means no bound
+type queryTimeout time.Duration
+
+// bound returns ctx with the timeout applied
+func (t queryTimeout) bound(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, time.Duration(t))
+}
+
+// issueContext returns the context for a query about to be issued: detached
+// from the worker's ctx, so a drain on Stop lets the query finish, but still
+// cancelled with stop. A nil stop leaves ctx as is.
+func issueContext(ctx, stop context.Context) (context.Context, context.CancelFunc) {
+	if stop == nil {
+		return ctx, func() {}
+	}
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	unhook := context.AfterFunc(stop, cancel)
+	return ctx, func() {
+		unhook()
+		cancel()
+	}
+}
+
+// label marks err as a query timeout if ctx (from bound) ran out, so timed
+// out queries stand out in recent errors
+func (t queryTimeout) label(ctx context.Context, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("query timeout after %s: %w", time.Duration(t), err)
+}
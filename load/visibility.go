@@ -0,0 +1,80 @@
+package load
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// visibilityTimeout is how long a reader keeps looking for a probed ID
+	visibilityTimeout = time.Second
+
+	// visibilityProbeBuffer bounds probes waiting for a reader; writers drop
+	// probes rather than block when it is full
+	visibilityProbeBuffer = 1024
+)
+
+// visibilityProbe is a freshly inserted ID handed from a writer to a reader
+// on a different connection to check read-your-writes consistency
+type visibilityProbe struct {
+	id        int64
+	committed time.Time // When the writer's insert returned committed
+}
+
+// publishProbe offers id to readers for a visibility check, sampled at the
+// configured rate. It never blocks.
+func (w *WriteWorker) publishProbe(id int64) {
+	if w.probes == nil || w.visibilityRate <= 0 || rand.Float64() >= w.visibilityRate {
+		return
+	}
+	select {
+	case w.probes <- visibilityProbe{id: id, committed: time.Now()}:
+	default:
+	}
+}
+
+// checkVisibility takes a pending probe, if any, and polls for its ID on
+// conn until it is visible or visibilityTimeout passes, recording the delay
+// since the writer committed it. It returns an error only if the connection
+// failed.
+func (w *ReadWorker) checkVisibility(ctx context.Context, conn *pgx.Conn) error {
+	var probe visibilityProbe
+	select {
+	case probe = <-w.probes:
+	default:
+		return nil
+	}
+
+	w.heartbeat.begin()
+	defer w.heartbeat.end()
+	if err := w.heartbeat.hold(ctx, w.paused); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	backoff := time.Millisecond
+	for {
+		var user User
+		err := conn.QueryRow(ctx, w.query, execArgs(w.prepared, probe.id)...).
+			Scan(&user.ID, &user.Username, &user.Email, &user.CreatedAt)
+		if err == nil {
+			w.collector.RecordVisibility(time.Since(probe.committed), true)
+			return nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		if time.Since(start) >= visibilityTimeout {
+			w.collector.RecordVisibility(time.Since(probe.committed), false)
+			return nil
+		}
+		if err := sleepContext(ctx, backoff); err != nil {
+			return err
+		}
+		backoff = min(backoff*2, 50*time.Millisecond)
+	}
+}
@@ -0,0 +1,36 @@
+package load
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"time"
+)
+
+// warmupTimeout bounds the warmup before a run
+const warmupTimeout = 30 * time.Second
+
+// warmup primes shared buffers and plans before a run by issuing n reads
+// spread uniformly over the ID range, touching the primary key index. It runs
+// on a dedicated connection to each of endpoints, or the primary if there are
+// none, until done or ctx ends, and nothing is recorded in metrics.
+func (c *Controller) warmup(ctx context.Context, n int, endpoints []Endpoint) {
+	targets := []Endpoint{{Name: "primary"}}
+	if len(endpoints) > 0 {
+		targets = endpoints
+	}
+	for _, ep := range targets {
+		if ctx.Err() != nil {
+			return
+		}
+		start := time.Now()
+		err := c.connMgr.Warmup(ctx, ep.URL, c.queries.Read, n, func() []any {
+			return []any{rand.Int63n(c.maxUserID) + 1}
+		})
+		if err != nil {
+			slog.Warn("Warmup stopped early", "endpoint", ep.Name, "error", err)
+			continue
+		}
+		slog.Info("Warmup finished", "endpoint", ep.Name, "reads", n, "elapsed", time.Since(start).Round(time.Millisecond))
+	}
+}
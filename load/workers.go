@@ -0,0 +1,216 @@
+package load
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// workerSetup is the per-run state shared by every worker spawned for it
+type workerSetup struct {
+	churnRate  float64 // Per-connection churn probability per second
+	batchQuery string  // Multi-row insert for batched writes
+	readSlots  *inflightLimiter
+	writeSlots *inflightLimiter
+}
+
+// workerRef lets the controller stop one worker without stopping the rest
+type workerRef struct {
+	cancel    context.CancelFunc
+	stop      context.Context // Cancelled with cancel, but not by a drain
+	heartbeat *heartbeat
+	handle    *connHandle // nil if not targeted by chaos mode
+}
+
+// resizable reports whether moving from oldConfig to newConfig only changes
+// the number of working or idle connections, in a mode where workers can be
+// added or removed one at a time: each holds its own connection, with no
+// churn rate spread across connections and no pools sized from them
+func resizable(oldConfig, newConfig Config) bool {
+	if newConfig.ChurnRate > 0 || newConfig.PoolMode || newConfig.AppInstances > 0 {
+		return false
+	}
+	oldConfig.Connections = newConfig.Connections
+	oldConfig.IdleConnections = newConfig.IdleConnections
+	return !workerConfigChanged(oldConfig, newConfig)
+}
+
+// splitConnections divides connections between readers and writers (80/20)
+func splitConnections(connections int) (readers, writers int) {
+	readers = (connections * 80) / 100
+	if readers < 1 && connections > 0 {
+		readers = 1
+	}
+	return readers, max(connections-readers, 0)
+}
+
+// resizeWorkers spawns or stops workers until the configured working and
+// idle connections are in use, leaving the rest running. New workers are
+// staggered across ramp. (caller holds c.mu)
+func (c *Controller) resizeWorkers(ramp time.Duration) {
+	idle := c.config.IdleConnections
+	for i := len(c.idleWorkers); i < idle; i++ {
+		c.spawnIdle(rampDelay(ramp, i, idle))
+	}
+	c.idleWorkers = c.trimWorkers(c.idleWorkers, idle)
+
+	// Every connection runs a weighted mix of operations
+	connections := c.config.Connections
+	if c.config.OperationWeights.Enabled() {
+		for i := len(c.mixedWorkers); i < connections; i++ {
+			c.spawnMixed(rampDelay(ramp, i, connections))
+		}
+		c.mixedWorkers = c.trimWorkers(c.mixedWorkers, connections)
+		return
+	}
+
+	numReaders, numWriters := splitConnections(connections)
+	for i := len(c.readWorkers); i < numReaders; i++ {
+		c.spawnReader(i, rampDelay(ramp, i, numReaders))
+	}
+	c.readWorkers = c.trimWorkers(c.readWorkers, numReaders)
+	for i := len(c.writeWorkers); i < numWriters; i++ {
+		c.spawnWriter(numReaders+i, rampDelay(ramp, i, numWriters))
+	}
+	c.writeWorkers = c.trimWorkers(c.writeWorkers, numWriters)
+}
+
+// trimWorkers stops the newest workers beyond n without waiting for them to
+// exit (caller holds c.mu)
+func (c *Controller) trimWorkers(workers []workerRef, n int) []workerRef {
+	for len(workers) > n {
+		last := workers[len(workers)-1]
+		last.cancel()
+		if last.handle != nil {
+			c.chaosHandles.remove(last.handle)
+		}
+		// Copy so WorkerHealth can keep reading the previous slice
+		c.heartbeats = slices.DeleteFunc(slices.Clone(c.heartbeats), func(hb *heartbeat) bool { return hb == last.heartbeat })
+		workers = workers[:len(workers)-1]
+	}
+	return workers
+}
+
+// newWorker registers a worker about to be spawned, returning the context
+// that stops it alone. A drain cancels the context but not ref.stop, which
+// its in-flight queries run under (caller holds c.mu)
+func (c *Controller) newWorker(pool connPool) (context.Context, workerRef) {
+	ctx, cancelCtx := context.WithCancel(c.issueCtx)
+	stop, cancelStop := context.WithCancel(c.ctx)
+	cancel := func() {
+		cancelCtx()
+		cancelStop()
+	}
+	ref := workerRef{cancel: cancel, stop: stop, heartbeat: c.newHeartbeat()}
+	if c.chaosHandles != nil && pool == nil && !c.config.PerQueryConnect {
+		ref.handle = &connHandle{}
+		c.chaosHandles.add(ref.handle)
+	}
+	return ctx, ref
+}
+
+// spawnReader starts the nth worker as a reader after delay (caller holds c.mu)
+func (c *Controller) spawnReader(n int, delay time.Duration) {
+	pool := c.poolFor(n)
+	var endpoint *Endpoint
+	if pool == nil {
+		endpoint = c.endpointFor(n)
+	}
+	ctx, ref := c.newWorker(pool)
+	c.readWorkers = append(c.readWorkers, ref)
+
+	cfg, queries, s := c.config, c.queries, c.setup
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer c.liveWorkers.Add(-1)
+		if sleepContext(ctx, delay) != nil {
+			return
+		}
+		worker := NewReadWorker(c.connMgr, c.readLimiter, &c.readsPaused, c.collector, queries.Read, c.maxUserID, c.ids, s.churnRate, cfg)
+		worker.rangeQuery = queries.ReadRange
+		worker.inListQuery = queries.ReadIn
+		worker.pool = pool
+		worker.probes = c.probes
+		worker.endpoint = endpoint
+		worker.heartbeat = ref.heartbeat
+		worker.stop = ref.stop
+		worker.inflight = s.readSlots
+		worker.handle = ref.handle
+		worker.Run(ctx)
+	}()
+}
+
+// spawnWriter starts the nth worker as a writer after delay (caller holds c.mu)
+func (c *Controller) spawnWriter(n int, delay time.Duration) {
+	pool := c.poolFor(n)
+	ctx, ref := c.newWorker(pool)
+	c.writeWorkers = append(c.writeWorkers, ref)
+
+	cfg, queries, s := c.config, c.queries, c.setup
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer c.liveWorkers.Add(-1)
+		if sleepContext(ctx, delay) != nil {
+			return
+		}
+		worker := NewWriteWorker(c.connMgr, c.writeLimiter, &c.writesPaused, c.collector, queries.insert(cfg), c.ids, s.churnRate, cfg)
+		worker.pool = pool
+		worker.probes = c.probes
+		worker.heartbeat = ref.heartbeat
+		worker.stop = ref.stop
+		worker.inflight = s.writeSlots
+		worker.batchQuery = s.batchQuery
+		worker.handle = ref.handle
+		worker.Run(ctx)
+	}()
+}
+
+// spawnMixed starts a mixed worker after delay (caller holds c.mu)
+func (c *Controller) spawnMixed(delay time.Duration) {
+	ctx, ref := c.newWorker(nil)
+	c.mixedWorkers = append(c.mixedWorkers, ref)
+
+	cfg, queries, s := c.config, c.queries, c.setup
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer c.liveWorkers.Add(-1)
+		if sleepContext(ctx, delay) != nil {
+			return
+		}
+		reader := NewReadWorker(c.connMgr, c.readLimiter, &c.readsPaused, c.collector, queries.Read, c.maxUserID, c.ids, s.churnRate, cfg)
+		reader.rangeQuery = queries.ReadRange
+		reader.inListQuery = queries.ReadIn
+		reader.heartbeat = ref.heartbeat
+		reader.stop = ref.stop
+		reader.inflight = s.readSlots
+		writer := NewWriteWorker(c.connMgr, c.writeLimiter, &c.writesPaused, c.collector, queries.insert(cfg), c.ids, s.churnRate, cfg)
+		writer.heartbeat = ref.heartbeat
+		writer.stop = ref.stop
+		writer.inflight = s.writeSlots
+		writer.batchQuery = s.batchQuery
+		worker := NewMixedWorker(reader, writer, cfg.OperationWeights, queries.Update)
+		worker.handle = ref.handle
+		worker.Run(ctx)
+	}()
+}
+
+// spawnIdle starts an idle worker after delay (caller holds c.mu)
+func (c *Controller) spawnIdle(delay time.Duration) {
+	ctx, ref := c.newWorker(nil)
+	c.idleWorkers = append(c.idleWorkers, ref)
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer c.liveWorkers.Add(-1)
+		if sleepContext(ctx, delay) != nil {
+			return
+		}
+		worker := NewIdleWorker(c.connMgr, c.collector)
+		worker.handle = ref.handle
+		worker.Run(ctx)
+	}()
+}
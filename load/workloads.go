@@ -0,0 +1,145 @@
+package load
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"supafirehose/metrics"
+)
+
+// DefaultWorkload names the primary workload driven by the top-level API
+const DefaultWorkload = "default"
+
+var workloadNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Workloads manages named workloads that run concurrently. Each has its own
+// controller, connection budget, and metrics. The default workload is the
+// primary controller and cannot be removed.
+type Workloads struct {
+	mu            sync.RWMutex
+	byName        map[string]*Controller
+	newController func() *Controller
+}
+
+// NewWorkloads creates a workload set around the default controller;
+// newController builds the controller for each additional workload
+func NewWorkloads(defaultController *Controller, newController func() *Controller) *Workloads {
+	return &Workloads{
+		byName:        map[string]*Controller{DefaultWorkload: defaultController},
+		newController: newController,
+	}
+}
+
+// Get returns the named workload's controller
+func (w *Workloads) Get(name string) (*Controller, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	c, ok := w.byName[name]
+	return c, ok
+}
+
+// New builds a controller for a workload called name without registering
+// it, so its first config can be checked before the workload exists
+func (w *Workloads) New(name string) (*Controller, error) {
+	if !workloadNameRe.MatchString(name) {
+		return nil, fmt.Errorf("workload name must be 1-32 lowercase letters, digits, '-' or '_'")
+	}
+	return w.newController(), nil
+}
+
+// Add registers c under name and returns it. If another request registered
+// the name first, that workload's controller is returned instead.
+func (w *Workloads) Add(name string, c *Controller) *Controller {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if existing, ok := w.byName[name]; ok {
+		return existing
+	}
+	w.byName[name] = c
+	return c
+}
+
+// Remove stops and deletes the named workload. The default workload cannot be removed.
+func (w *Workloads) Remove(name string) error {
+	if name == DefaultWorkload {
+		return fmt.Errorf("the %s workload cannot be removed", DefaultWorkload)
+	}
+
+	w.mu.Lock()
+	c, ok := w.byName[name]
+	delete(w.byName, name)
+	w.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("workload %q not found", name)
+	}
+	c.Stop()
+	return nil
+}
+
+// Names returns all workload names, sorted
+func (w *Workloads) Names() []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	names := make([]string, 0, len(w.byName))
+	for name := range w.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DBConnections totals the database connections configured across every
+// workload except the named one
+func (w *Workloads) DBConnections(except string) int {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	total := 0
+	for name, c := range w.byName {
+		if name != except {
+			total += c.GetConfig().DBConnections()
+		}
+	}
+	return total
+}
+
+// CachedIDs totals the cached ID counts and memory estimates across all workloads
+func (w *Workloads) CachedIDs() (count int, bytes int64) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for _, c := range w.byName {
+		n, b := c.CachedIDs()
+		count += n
+		bytes += b
+	}
+	return count, bytes
+}
+
+// StopAll stops every workload, draining them concurrently so shutdown takes
+// one drain timeout rather than one per workload
+func (w *Workloads) StopAll() {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	var wg sync.WaitGroup
+	for _, c := range w.byName {
+		wg.Go(c.Stop)
+	}
+	wg.Wait()
+}
+
+// Collectors returns the metrics collector of every workload except the
+// default one, keyed by name
+func (w *Workloads) Collectors() map[string]*metrics.Collector {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	collectors := make(map[string]*metrics.Collector, len(w.byName)-1)
+	for name, c := range w.byName {
+		if name != DefaultWorkload {
+			collectors[name] = c.collector
+		}
+	}
+	return collectors
+}
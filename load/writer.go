@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"sync/atomic"
 	"time"
 
 	"supafirehose/db"
@@ -15,24 +16,74 @@ import (
 
 // WriteWorker executes write queries against the database
 type WriteWorker struct {
-	connMgr   *db.ConnectionManager
-	limiter   *rate.Limiter
-	collector *metrics.Collector
-	churnRate float64 // Probability of churning connection per second
+	connMgr         *db.ConnectionManager
+	limiter         *rate.Limiter
+	paused          *atomic.Bool // Skip issuing writes while set
+	collector       *metrics.Collector
+	query           string
+	ids             *idCache
+	lifetime        connLifetime // When to churn the persistent connection
+	perQueryConnect bool         // Open a new connection for every query
+	latency         latencyInjector
+	explainRate     float64                // Fraction of writes run under EXPLAIN ANALYZE
+	maxRetries      int                    // Retries for serialization failures and deadlocks
+	pool            connPool               // Borrow a connection per query (nil = own connection)
+	probes          chan<- visibilityProbe // Fresh IDs offered to readers for visibility checks
+	visibilityRate  float64                // Fraction of inserts offered as probes
+	errorRate       float64                // Fraction of writes deliberately made to fail
+	upsert          bool                   // query is an upsert taking the target ID first
+	conflictRate    float64                // Fraction of upserts given an existing ID
+	heartbeat       *heartbeat             // Marks in-flight queries for stuck-worker detection
+	txnSize         int                    // Inserts per transaction (<= 1 = autocommit)
+	batchSize       int                    // Rows per insert statement (<= 1 = single-row)
+	batchQuery      string                 // Multi-row insert of batchSize rows
+	handle          *connHandle            // Lets the chaos loop sever the persistent connection
+	inflight        *inflightLimiter       // Caps concurrent writes across workers (nil = unlimited)
+	slotWait        time.Duration          // How long the last write waited for an inflight slot
+	explained       bool                   // The last write ran under EXPLAIN ANALYZE
+	timeout         queryTimeout
+	stop            context.Context // Cancels an in-flight write; outlives ctx through a drain (nil = ctx)
+	retry           transientRetry  // Reissues writes that failed on a broken connection
+	prepared        bool            // Prepare queries once per persistent connection
 }
 
 // NewWriteWorker creates a new write worker
-func NewWriteWorker(connMgr *db.ConnectionManager, limiter *rate.Limiter, collector *metrics.Collector, churnRate float64) *WriteWorker {
+func NewWriteWorker(connMgr *db.ConnectionManager, limiter *rate.Limiter, paused *atomic.Bool, collector *metrics.Collector, query string, ids *idCache, churnRate float64, cfg Config) *WriteWorker {
 	return &WriteWorker{
-		connMgr:   connMgr,
-		limiter:   limiter,
-		collector: collector,
-		churnRate: churnRate,
+		connMgr:         connMgr,
+		limiter:         limiter,
+		paused:          paused,
+		collector:       collector,
+		query:           query,
+		ids:             ids,
+		lifetime:        newConnLifetime(churnRate, cfg),
+		perQueryConnect: cfg.PerQueryConnect,
+		latency:         newLatencyInjector(cfg),
+		explainRate:     cfg.ExplainSampleRate,
+		maxRetries:      cfg.WriteRetries,
+		visibilityRate:  cfg.VisibilityCheckRate,
+		errorRate:       cfg.ErrorInjectionRate,
+		upsert:          cfg.WriteMode == WriteModeUpsert,
+		conflictRate:    cfg.UpsertConflictRate,
+		txnSize:         cfg.StatementsPerTransaction,
+		batchSize:       cfg.WriteBatchSize,
+		timeout:         queryTimeout(time.Duration(cfg.QueryTimeoutMs) * time.Millisecond),
+		retry:           transientRetry{max: cfg.TransientRetries},
+		prepared:        cfg.PreparedStatements,
 	}
 }
 
 // Run starts the write worker loop with its own connection
 func (w *WriteWorker) Run(ctx context.Context) {
+	if w.pool != nil {
+		w.runPooled(ctx)
+		return
+	}
+	if w.perQueryConnect {
+		w.runPerQuery(ctx)
+		return
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -41,7 +92,9 @@ func (w *WriteWorker) Run(ctx context.Context) {
 		}
 
 		// Create a new connection
+		w.heartbeat.beginConnect()
 		conn, err := w.connMgr.Connect(ctx)
+		w.heartbeat.end()
 		if err != nil {
 			// Don't record context cancellation as error (expected during shutdown)
 			if ctx.Err() != nil {
@@ -53,70 +106,379 @@ func (w *WriteWorker) Run(ctx context.Context) {
 			continue
 		}
 
+		if recovered := w.handle.set(conn); recovered > 0 {
+			w.collector.RecordChaosReconnect(recovered)
+		}
+
 		// Run queries on this connection until churn or context done
+		connectedAt := time.Now()
 		w.runWithConnection(ctx, conn)
+		w.handle.clear()
 
 		// Close connection (use background context to ensure clean close)
 		conn.Close(context.Background())
 		w.connMgr.Release()
+		w.collector.RecordConnectionLifetime(time.Since(connectedAt))
+	}
+}
+
+// runPerQuery opens a fresh connection for every query and closes it right
+// after, measuring connect+query+close as the unit latency
+func (w *WriteWorker) runPerQuery(ctx context.Context) {
+	for {
+		if w.paused.Load() {
+			if err := sleepContext(ctx, pausePollInterval); err != nil {
+				return
+			}
+			continue
+		}
+
+		if err := w.limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		start := time.Now()
+		injected, err := w.latency.wait(ctx)
+		if err != nil {
+			return
+		}
+		w.collector.RecordWriteInjected(injected)
+
+		w.heartbeat.beginConnect()
+		conn, err := w.connMgr.Connect(ctx)
+		w.heartbeat.end()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			w.collector.RecordWrite(time.Since(start), err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		connectedAt := time.Now()
+		err = w.write(ctx, conn)
+		conn.Close(context.Background())
+		w.connMgr.Release()
+		w.collector.RecordConnectionLifetime(time.Since(connectedAt))
+		latency := time.Since(start) - w.slotWait
+
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+		w.record(latency, err)
+	}
+}
+
+// runPooled borrows a connection from the worker's app instance pool for each
+// query; time spent waiting for a free connection counts toward latency
+func (w *WriteWorker) runPooled(ctx context.Context) {
+	for {
+		if w.paused.Load() {
+			if err := sleepContext(ctx, pausePollInterval); err != nil {
+				return
+			}
+			continue
+		}
+
+		if err := w.limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		start := time.Now()
+		injected, err := w.latency.wait(ctx)
+		if err != nil {
+			return
+		}
+		w.collector.RecordWriteInjected(injected)
+
+		w.heartbeat.beginConnect()
+		pc, err := w.pool.get(ctx)
+		w.heartbeat.end()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			w.collector.RecordWrite(time.Since(start), err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		err = w.write(ctx, pc.conn)
+		latency := time.Since(start) - w.slotWait
+		w.pool.put(pc, err != nil)
+
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+		w.record(latency, err)
 	}
 }
 
 func (w *WriteWorker) runWithConnection(ctx context.Context, conn *pgx.Conn) {
-	// Calculate when to churn this connection
-	var churnAfter time.Time
-	if w.churnRate > 0 {
-		avgLifetime := time.Duration(float64(time.Second) / w.churnRate)
-		lifetime := time.Duration(rand.ExpFloat64() * float64(avgLifetime))
-		// Clamp lifetime to reasonable bounds
-		lifetime = max(lifetime, 100*time.Millisecond)
-		lifetime = min(lifetime, 60*time.Second)
-		churnAfter = time.Now().Add(lifetime)
+	if w.prepared {
+		if err := prepareAll(ctx, conn, w.statements()...); err != nil {
+			if ctx.Err() == nil {
+				w.collector.RecordWrite(0, err)
+				sleepContext(ctx, prepareFailedBackoff)
+			}
+			return
+		}
 	}
 
+	// Calculate when to churn this connection
+	churnAfter := w.lifetime.deadline(time.Now())
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
 			// Check if it's time to churn
-			if w.churnRate > 0 && time.Now().After(churnAfter) {
+			if !churnAfter.IsZero() && time.Now().After(churnAfter) {
 				return // Exit to churn connection
 			}
 
+			// Reissue a write that failed on the previous connection
+			if start, ok := w.retry.pending(); ok {
+				if w.retry.wait(ctx) != nil {
+					return
+				}
+				if err := w.executeWrite(ctx, conn, start); err != nil {
+					return
+				}
+				continue
+			}
+
+			// Hold the connection open but issue nothing while paused
+			if w.paused.Load() {
+				if err := sleepContext(ctx, pausePollInterval); err != nil {
+					return
+				}
+				continue
+			}
+
 			// Wait for rate limiter
 			if err := w.limiter.Wait(ctx); err != nil {
 				return
 			}
 
-			// Execute query; abandon connection on error to force reconnect
-			if err := w.executeWrite(ctx, conn); err != nil {
+			// Simulate network distance; the delay counts toward measured latency
+			start := time.Now()
+			injected, err := w.latency.wait(ctx)
+			if err != nil {
+				return
+			}
+			w.collector.RecordWriteInjected(injected)
+
+			// Execute query; abandon the connection if it broke to force a reconnect
+			if err := w.executeWrite(ctx, conn, start); err != nil {
 				return
 			}
 		}
 	}
 }
 
-func (w *WriteWorker) executeWrite(ctx context.Context, conn *pgx.Conn) error {
-	start := time.Now()
+func (w *WriteWorker) executeWrite(ctx context.Context, conn *pgx.Conn, start time.Time) error {
+	err := w.write(ctx, conn)
+	latency := time.Since(start) - w.slotWait
+
+	// Don't record context cancellation as an error (expected during shutdown)
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+	if w.retry.postpone(err, start, true) {
+		w.collector.RecordWriteRetry()
+		return err
+	}
+	w.record(latency, err)
+	return connErr(conn, err)
+}
+
+// statements lists the queries this worker issues, for preparing
+func (w *WriteWorker) statements() []string {
+	queries := []string{w.query, w.batchQuery}
+	if w.explainRate > 0 {
+		queries = append(queries, explainPrefix+w.query)
+	}
+	return queries
+}
+
+// record records a write, only counting one run under EXPLAIN ANALYZE so
+// its latency stays out of the histogram
+func (w *WriteWorker) record(latency time.Duration, err error) {
+	if w.explained {
+		w.explained = false
+		w.collector.RecordExplainedWrite(err)
+		return
+	}
+	w.collector.RecordWrite(latency, err)
+}
+
+// write issues a single insert on conn (with retries) without recording its latency
+func (w *WriteWorker) write(ctx context.Context, conn *pgx.Conn) (err error) {
+	// The wait is reported as a concurrency wait, not write latency
+	w.slotWait, err = w.inflight.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer w.inflight.release()
+
+	w.heartbeat.begin()
+	defer w.heartbeat.end()
+	if err := w.heartbeat.hold(ctx, w.paused); err != nil {
+		return err
+	}
+
+	ctx, detach := issueContext(ctx, w.stop)
+	defer detach()
+	ctx, cancel := w.timeout.bound(ctx)
+	defer cancel()
+	defer func() { err = w.timeout.label(ctx, err) }()
+
+	w.explained = false
 
 	// Generate random user data
 	randNum := rand.Int63()
 	username := fmt.Sprintf("user_%d", randNum)
 	email := fmt.Sprintf("user_%d@example.com", randNum)
 
-	var newID int64
-	err := conn.QueryRow(ctx,
-		"INSERT INTO users (username, email) VALUES ($1, $2) RETURNING id",
-		username, email,
-	).Scan(&newID)
-
-	latency := time.Since(start)
+	// Deliberately violate NOT NULL on a fraction of writes to exercise the
+	// error path end to end (the server rejects the insert)
+	if w.errorRate > 0 && rand.Float64() < w.errorRate {
+		var newID int64
+		return conn.QueryRow(ctx, w.query, execArgs(w.prepared, w.args(username, nil)...)...).Scan(&newID)
+	}
 
-	// Don't record context cancellation as an error (expected during shutdown)
-	if err != nil && ctx.Err() != nil {
+	if w.txnSize > 1 {
+		return w.writeRows(ctx, func() ([]int64, error) { return w.insertBatch(ctx, conn) })
+	}
+	if w.batchSize > 1 {
+		args := batchArgs(w.batchSize)
+		err := w.writeRows(ctx, func() ([]int64, error) { return insertMulti(ctx, conn, w.batchQuery, execArgs(w.prepared, args...)) })
+		if err == nil {
+			w.collector.RecordWriteBytes(valueBytes(args...))
+		}
 		return err
 	}
-	w.collector.RecordWrite(latency, err)
+
+	// Sample a fraction of writes under EXPLAIN ANALYZE (the insert still executes)
+	w.explained = w.explainRate > 0 && rand.Float64() < w.explainRate
+	args := w.args(username, email)
+
+	var planning, execution time.Duration
+	for attempt := 0; ; attempt++ {
+		if w.explained {
+			planning, execution, err = explainAnalyze(ctx, conn, w.prepared, w.query, args...)
+		} else {
+			var newID int64
+			err = conn.QueryRow(ctx, w.query, execArgs(w.prepared, args...)...).Scan(&newID)
+			if err == nil {
+				w.ids.Add(newID)
+				w.publishProbe(newID)
+				w.collector.RecordWriteBytes(valueBytes(args...))
+			}
+		}
+
+		// Retry contention failures like a real app would
+		if err == nil || attempt >= w.maxRetries || !isRetryableWriteError(err) {
+			break
+		}
+		w.collector.RecordWriteRetry()
+		if sleepErr := sleepContext(ctx, retryBackoff(attempt)); sleepErr != nil {
+			return sleepErr
+		}
+	}
+
+	if w.explained && err == nil {
+		w.collector.RecordWritePlan(planning, execution)
+	}
 	return err
 }
+
+// args returns the arguments for a single-row write. Upserts take the
+// target ID first: an existing one from the cache for conflictRate of them,
+// otherwise NULL so a new row is inserted.
+func (w *WriteWorker) args(username, email any) []any {
+	if !w.upsert {
+		return []any{username, email}
+	}
+	var id any
+	if rand.Float64() < w.conflictRate {
+		if cached, ok := w.ids.Recent(idCacheSize); ok {
+			id = cached
+		}
+	}
+	return []any{id, username, email}
+}
+
+// writeRows runs a multi-row insert (a transaction or a batched statement),
+// retrying it whole on contention failures, and records the rows committed
+func (w *WriteWorker) writeRows(ctx context.Context, insert func() ([]int64, error)) error {
+	for attempt := 0; ; attempt++ {
+		ids, err := insert()
+		if err == nil {
+			for _, id := range ids {
+				w.ids.Add(id)
+				w.publishProbe(id)
+			}
+			w.collector.RecordWriteRows(len(ids))
+			return nil
+		}
+
+		if attempt >= w.maxRetries || !isRetryableWriteError(err) {
+			return err
+		}
+		w.collector.RecordWriteRetry()
+		if sleepErr := sleepContext(ctx, retryBackoff(attempt)); sleepErr != nil {
+			return sleepErr
+		}
+	}
+}
+
+// insertBatch runs one transaction of txnSize inserts and returns the new IDs
+func (w *WriteWorker) insertBatch(ctx context.Context, conn *pgx.Conn) ([]int64, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx) // no-op once committed
+
+	ids := make([]int64, 0, w.txnSize)
+	size := 0
+	for i := 0; i < w.txnSize; i++ {
+		randNum := rand.Int63()
+		username, email := fmt.Sprintf("user_%d", randNum), fmt.Sprintf("user_%d@example.com", randNum)
+		var id int64
+		err := tx.QueryRow(ctx, w.query, execArgs(w.prepared, username, email)...).Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		size += valueBytes(username, email)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	w.collector.RecordWriteBytes(size)
+	return ids, nil
+}
+
+// batchArgs generates usernames and emails for n rows of a multi-row insert
+func batchArgs(n int) []any {
+	args := make([]any, 0, 2*n)
+	for i := 0; i < n; i++ {
+		randNum := rand.Int63()
+		args = append(args, fmt.Sprintf("user_%d", randNum), fmt.Sprintf("user_%d@example.com", randNum))
+	}
+	return args
+}
+
+// insertMulti runs a multi-row insert and returns the new IDs
+func insertMulti(ctx context.Context, conn *pgx.Conn, query string, args []any) ([]int64, error) {
+	rows, err := conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[int64])
+}
@@ -3,15 +3,18 @@ package main
 import (
 	"context"
 	"embed"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/fs"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -28,62 +31,306 @@ var frontendFS embed.FS
 func main() {
 	// Parse flags
 	devMode := flag.Bool("dev", false, "Development mode (proxy frontend to Vite)")
+	findMaxQPS := flag.Bool("find-max-qps", false, "Search for the maximum sustainable QPS, print it, and exit (no server)")
+	searchHold := flag.Duration("search-hold", 10*time.Second, "How long each -find-max-qps step is measured")
+	searchMaxErrors := flag.Float64("search-max-error-rate", 0.01, "Highest error rate a -find-max-qps step may have and still pass")
+	searchMinQPS := flag.Int("search-min-qps", 0, "Fail -find-max-qps (and its -junit check) if the max sustainable QPS is below this")
+	junitPath := flag.String("junit", "", "Write the -find-max-qps outcome to this file as JUnit XML, for CI result viewers")
+	setup := flag.Bool("setup", false, "Create the users table and seed it with MAX_USER_ID rows if missing, then exit (no server)")
+	compare := flag.String("compare", "", "Compare two summaries exported from /api/summary, given as a.json,b.json, print the comparison as JSON, and exit (no database)")
+	skipIndexes := flag.Bool("skip-indexes", false, "With -setup, create the users table without its unique email index (like init.sql's skip_indexes)")
 	flag.Parse()
+	defer runExitHooks()
 
 	// Load configuration
 	cfg := config.Load()
 
-	log.Printf("Starting SupaFirehose on port %d", cfg.HTTPPort)
+	// Route slog (and anything still using the log package) through LOG_FORMAT/LOG_LEVEL
+	logger, err := config.NewLogger(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		slog.Error("Invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	if *compare != "" {
+		runCompare(*compare)
+		return
+	}
+
+	slog.Info("Starting SupaFirehose", "port", cfg.HTTPPort)
 
 	// Create connection manager (no pool - direct connections)
-	connMgr := db.NewConnectionManager(cfg.DatabaseURL)
+	connMgr := db.NewConnectionManager(cfg.PrimaryURL())
+	connMgr.SetDialConcurrency(cfg.ReconnectConcurrency)
+	connMgr.SetFailureLogSample(cfg.ConnLogSample)
 
 	// Verify database connectivity
 	ctx := context.Background()
 	if err := connMgr.Ping(ctx); err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to connect to database", "error", err)
+	}
+	slog.Info("Connected to database")
+
+	// Bootstrap a fresh database instead of running psql -f init.sql
+	if *setup {
+		seeded, err := connMgr.Bootstrap(ctx, cfg.UsersTable(), cfg.Tablespace, cfg.MaxUserID, !*skipIndexes)
+		if err != nil {
+			fatal("Setup failed", "error", err)
+		}
+		slog.Info("Setup complete", "table", cfg.UsersTable(), "seeded_rows", seeded)
+		return
+	}
+
+	// Make sure the workload table is on the requested tablespace
+	if cfg.Tablespace != "" {
+		actual, err := connMgr.CheckTablespace(ctx, cfg.Tablespace, cfg.UsersTable())
+		if err != nil {
+			fatal("Tablespace check failed", "error", err)
+		}
+		if actual != cfg.Tablespace {
+			slog.Warn("Table is on the wrong tablespace; recreate it with init.sql -v tablespace=<name>",
+				"table", cfg.UsersTable(), "tablespace", actual, "expected", cfg.Tablespace)
+		}
+	}
+
+	// Let dead tuples pile up unchecked for worst-case bloat runs
+	if cfg.DisableAutovacuum {
+		if !cfg.AllowDestructive {
+			fatal("DISABLE_AUTOVACUUM alters the workload table; set ALLOW_DESTRUCTIVE=true to allow it", "table", cfg.UsersTable())
+		}
+		if err := connMgr.SetAutovacuum(ctx, cfg.UsersTable(), false); err != nil {
+			fatal("Failed to disable autovacuum", "error", err)
+		}
+		slog.Info("Autovacuum disabled until shutdown", "table", cfg.UsersTable())
+
+		// Turn it back on however we exit, including through fatal
+		onExit(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := connMgr.SetAutovacuum(ctx, cfg.UsersTable(), true); err != nil {
+				slog.Warn("Could not re-enable autovacuum", "table", cfg.UsersTable(), "error", err)
+			}
+		})
+	}
+
+	// Detect server connection headroom so the API can warn about doomed configs
+	var serverLimits *db.ServerLimits
+	if limits, err := connMgr.ProbeServerLimits(ctx); err != nil {
+		slog.Warn("Could not detect server connection limits", "error", err)
+	} else {
+		serverLimits = &limits
+		slog.Info("Server connections",
+			"max_connections", limits.MaxConnections,
+			"in_use", limits.CurrentConnections,
+			"reserved", limits.ReservedConnections,
+			"available", limits.AvailableConnections)
 	}
-	log.Printf("Connected to database")
 
 	// Create metrics collector with connection stats function
 	collector := metrics.NewCollector(func() metrics.PoolStats {
 		return metrics.PoolStats{
-			ActiveConnections: connMgr.ActiveConnections(),
-			IdleConnections:   0,
-			WaitingRequests:   0,
+			ActiveConnections:  connMgr.ActiveConnections(),
+			IdleConnections:    connMgr.IdleConnections(),
+			WaitingRequests:    connMgr.WaitingConnections(),
+			AcquireWaitAvg:     float64(connMgr.TakeAcquireWait().Microseconds()) / 1000.0,
+			PoolAcquireWaitAvg: float64(connMgr.TakePoolAcquireWait().Microseconds()) / 1000.0,
+			DialWaits:          connMgr.TakeDialWaits(),
 		}
 	})
+	collector.SetQPSSmoothing(cfg.QPSSmoothingAlpha)
+	collector.SetHistorySize(cfg.MetricsHistorySize)
+	collector.SetRecentErrors(cfg.RecentErrorsMax, cfg.ErrorSampleInterval)
+	observeConnectPhases(connMgr, collector)
+
+	// Periodically sample dead tuples / vacuum activity on the workload table
+	if cfg.TableStatsInterval > 0 {
+		go connMgr.MonitorTable(ctx, cfg.UsersTable(), cfg.TableStatsInterval, func(stats db.TableStats) {
+			collector.SetTableStats(metrics.TableStats(stats))
+		})
+	}
+
+	// Periodically sample WAL position to report write amplification as bytes/sec
+	if cfg.WALStatsInterval > 0 {
+		go connMgr.MonitorWAL(ctx, cfg.WALStatsInterval, func(stats db.WALStats) {
+			collector.SetWALStats(metrics.WALStats(stats))
+		})
+	}
+
+	// Poll target-side CPU/memory so the dashboard can overlay DB saturation
+	if poller := metrics.NewTargetPoller(cfg.TargetMetricsURL, cfg.TargetMetricsCommand, cfg.TargetPollInterval); poller != nil && cfg.TargetPollInterval > 0 {
+		go collector.PollTarget(ctx, poller, cfg.TargetPollInterval)
+	}
 
 	// Create load controller
-	controller := load.NewController(connMgr, collector, cfg.MaxUserID)
-	controller.SetConfig(load.Config{
+	controller := load.NewController(connMgr, collector, cfg.MaxUserID, cfg.UsersTable())
+
+	// Past MAX_RUNTIME no workload can be started again, even if the process
+	// keeps serving the UI
+	var deadline time.Time
+	if cfg.MaxRuntime > 0 {
+		deadline = time.Now().Add(cfg.MaxRuntime)
+	}
+	controller.SetDeadline(deadline)
+
+	// Spread reads across weighted regional endpoints with per-endpoint metrics
+	endpoints, err := config.ParseEndpoints(cfg.ReadEndpoints)
+	if err != nil {
+		fatal("Invalid READ_ENDPOINTS", "error", err)
+	}
+
+	// A separate read URL is a single endpoint taking every read
+	if cfg.ReadDatabaseURL != "" {
+		if len(endpoints) > 0 {
+			fatal("Set READ_DATABASE_URL or READ_ENDPOINTS, not both")
+		}
+		endpoints = []load.Endpoint{{Name: "replica", URL: cfg.ReadDatabaseURL, Weight: 1}}
+	}
+	var endpointNames []string
+	if len(endpoints) > 0 {
+		endpointNames = make([]string, len(endpoints))
+		for i, ep := range endpoints {
+			endpointNames[i] = ep.Name
+			slog.Info("Read endpoint", "endpoint", ep.Name, "weight", ep.Weight)
+		}
+		collector.RegisterEndpoints(endpointNames)
+		controller.SetReadEndpoints(endpoints)
+	}
+
+	// Each connection runs a weighted mix of operations instead of only reads or writes
+	weights, err := config.ParseOperationWeights(cfg.OperationWeights)
+	if err != nil {
+		fatal("Invalid OPERATION_WEIGHTS", "error", err)
+	}
+
+	defaults := load.Config{
 		Connections: cfg.DefaultConnections,
 		ReadQPS:     cfg.DefaultReadQPS,
 		WriteQPS:    cfg.DefaultWriteQPS,
+		ReadRatio:   load.DefaultReadRatio,
 		ChurnRate:   0,
+
+		IdleConnections: cfg.IdleConnections,
+
+		ExplainSampleRate: cfg.ExplainSampleRate,
+		WriteRetries:      cfg.WriteRetries,
+		TransientRetries:  cfg.TransientRetries,
+		WarmupQueries:     cfg.WarmupQueries,
+		WarmupSeconds:     cfg.WarmupSeconds,
+		RampSeconds:       cfg.RampSeconds,
+
+		MaxConcurrentReads:  cfg.MaxConcurrentReads,
+		MaxConcurrentWrites: cfg.MaxConcurrentWrites,
+
+		QueryTimeoutMs: cfg.QueryTimeoutMs,
+		DrainTimeoutMs: cfg.DrainTimeoutMs,
+
+		InjectLatencyMs:       cfg.InjectLatencyMs,
+		InjectLatencyJitterMs: cfg.InjectLatencyJitterMs,
+
+		MaxConnLifetimeSec:       cfg.MaxConnLifetimeSec,
+		MaxConnLifetimeJitterPct: cfg.MaxConnLifetimeJitterPct,
+
+		PerQueryConnect: cfg.PerQueryConnect,
+		PoolMode:        cfg.PoolMode,
+		ReadStrategy:    cfg.ReadStrategy,
+		RecentWindow:    cfg.RecentWindow,
+		ReadMode:        cfg.ReadMode,
+		ReadRangeSize:   cfg.ReadRangeSize,
+		ReadInListSize:  cfg.ReadInListSize,
+
+		PreparedStatements: cfg.PreparedStatements,
+
+		AppInstances:           cfg.AppInstances,
+		ConnectionsPerInstance: cfg.ConnectionsPerInstance,
+
+		VisibilityCheckRate: cfg.VisibilityCheckRate,
+		ErrorInjectionRate:  cfg.ErrorInjectionRate,
+
+		WriteMode:          cfg.WriteMode,
+		UpsertConflictRate: cfg.UpsertConflictRate,
+
+		ChaosIntervalSec: cfg.ChaosIntervalSec,
+		ChaosFraction:    cfg.ChaosFraction,
+
+		StatementsPerTransaction: cfg.StatementsPerTransaction,
+		WriteBatchSize:           cfg.WriteBatchSize,
+
+		OperationWeights: weights,
+	}
+	controller.SetConfig(defaults)
+
+	// Additional named workloads each get their own connection budget and metrics
+	workloads := load.NewWorkloads(controller, func() *load.Controller {
+		cm := db.NewConnectionManager(cfg.PrimaryURL())
+		cm.SetDialConcurrency(cfg.ReconnectConcurrency)
+		cm.SetFailureLogSample(cfg.ConnLogSample)
+		col := metrics.NewCollector(func() metrics.PoolStats {
+			return metrics.PoolStats{
+				ActiveConnections:  cm.ActiveConnections(),
+				IdleConnections:    cm.IdleConnections(),
+				WaitingRequests:    cm.WaitingConnections(),
+				AcquireWaitAvg:     float64(cm.TakeAcquireWait().Microseconds()) / 1000.0,
+				PoolAcquireWaitAvg: float64(cm.TakePoolAcquireWait().Microseconds()) / 1000.0,
+				DialWaits:          cm.TakeDialWaits(),
+			}
+		})
+		col.SetQPSSmoothing(cfg.QPSSmoothingAlpha)
+		col.SetHistorySize(0) // Only the main workload's history is served
+		col.SetRecentErrors(cfg.RecentErrorsMax, cfg.ErrorSampleInterval)
+		observeConnectPhases(cm, col)
+		c := load.NewController(cm, col, cfg.MaxUserID, cfg.UsersTable())
+		c.SetDeadline(deadline)
+		if len(endpoints) > 0 {
+			col.RegisterEndpoints(endpointNames)
+			c.SetReadEndpoints(endpoints)
+		}
+		c.SetConfig(defaults)
+		return c
 	})
 
+	// Headless capacity search: drive the controller directly and exit
+	if *findMaxQPS {
+		runFindMaxQPS(controller, cfg, *searchHold, *searchMaxErrors, *searchMinQPS, *junitPath)
+		return
+	}
+
 	// Create API handlers
-	handlers := api.NewHandlers(controller, collector)
+	handlers := api.NewHandlers(controller, collector, connMgr, serverLimits, workloads)
+	handlers.SetConfigLimits(api.ConfigLimits{
+		MaxConnections: cfg.MaxConnections,
+		MaxReadQPS:     cfg.MaxReadQPS,
+		MaxWriteQPS:    cfg.MaxWriteQPS,
+	})
+	handlers.SetAllowDBTest(cfg.AllowDBTest)
+
+	// Push snapshots to StatsD when configured (no-op otherwise)
+	exporter, err := metrics.NewStatsDExporter(cfg.StatsDAddr, cfg.StatsDPrefix)
+	if err != nil {
+		fatal("StatsD exporter", "error", err)
+	}
+	if cfg.StatsDAddr != "" {
+		slog.Info("Exporting metrics to StatsD", "addr", cfg.StatsDAddr)
+	}
 
 	// Create WebSocket hub
-	wsHub := api.NewWebSocketHub(collector, cfg.MetricsInterval)
+	wsHub := api.NewWebSocketHub(collector, cfg.MetricsInterval, exporter, workloads)
 	go wsHub.StartBroadcast()
+	handlers.SetMetricsHub(wsHub)
 
 	// Set up router
 	var staticFS fs.FS
 	if *devMode {
-		log.Printf("Development mode: proxying frontend to http://localhost:5173")
+		slog.Info("Development mode: proxying frontend", "url", "http://localhost:5173")
 	} else {
 		// Use embedded frontend
-		var err error
 		staticFS, err = fs.Sub(frontendFS, "frontend/dist")
 		if err != nil {
-			log.Printf("Warning: No embedded frontend found: %v", err)
+			slog.Warn("No embedded frontend found", "error", err)
 		}
 	}
 
-	router := api.NewRouter(handlers, wsHub, staticFS)
+	router := api.NewRouter(handlers, wsHub, staticFS, cfg.BasePath)
 
 	// In dev mode, proxy non-API requests to Vite
 	var handler http.Handler = router
@@ -91,20 +338,45 @@ func main() {
 		handler = devModeHandler(router)
 	}
 
+	// Mount everything under BASE_PATH when reverse-proxied on a subpath
+	handler = api.WithBasePath(handler, cfg.BasePath)
+	if cfg.BasePath != "" {
+		slog.Info("Serving under base path", "base_path", cfg.BasePath)
+	}
+
 	// Create server
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", cfg.HTTPPort),
 		Handler: handler,
 	}
 
+	// Safety valve: stop load (and optionally exit) after MAX_RUNTIME no matter what
+	var maxRuntime <-chan time.Time
+	if cfg.MaxRuntime > 0 {
+		maxRuntime = time.After(time.Until(deadline))
+		slog.Info("Max runtime set", "max_runtime", cfg.MaxRuntime, "exit", cfg.MaxRuntimeExit)
+	}
+
 	// Graceful shutdown
 	go func() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		<-sigChan
 
-		log.Println("Shutting down...")
-		controller.Stop()
+		select {
+		case <-sigChan:
+		case <-maxRuntime:
+			slog.Info("Max runtime reached, stopping load", "max_runtime", cfg.MaxRuntime)
+			workloads.StopAll()
+			if !cfg.MaxRuntimeExit {
+				// Keep serving the UI/API; only a signal shuts down now
+				<-sigChan
+			}
+		}
+
+		slog.Info("Shutting down")
+		workloads.StopAll()
+
+		runExitHooks()
 
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
@@ -112,9 +384,112 @@ func main() {
 	}()
 
 	// Start server
-	log.Printf("Server listening on http://localhost:%d", cfg.HTTPPort)
+	slog.Info("Server listening", "url", fmt.Sprintf("http://localhost:%d", cfg.HTTPPort))
 	if err := server.ListenAndServe(); err != http.ErrServerClosed {
-		log.Fatalf("Server error: %v", err)
+		fatal("Server error", "error", err)
+	}
+}
+
+// exitHooks undo changes made to the database for a run. They are registered
+// during startup, before anything can run them.
+var exitHooks []func()
+
+// onExit registers fn to run once when the process exits, whether by signal,
+// by main returning, or through fatal (os.Exit skips deferred calls)
+func onExit(fn func()) {
+	exitHooks = append(exitHooks, sync.OnceFunc(fn))
+}
+
+// runExitHooks runs the registered exit hooks, newest first
+func runExitHooks() {
+	for i := len(exitHooks) - 1; i >= 0; i-- {
+		exitHooks[i]()
+	}
+}
+
+// fatal logs msg at error level with the given attributes, runs the exit
+// hooks, and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	runExitHooks()
+	os.Exit(1)
+}
+
+// observeConnectPhases reports the duration of cm's connects, and their
+// dial/TLS/startup breakdown, to collector
+func observeConnectPhases(cm *db.ConnectionManager, collector *metrics.Collector) {
+	cm.SetConnectObserver(func(p db.ConnectPhases) {
+		collector.RecordConnect(p.Total)
+		collector.RecordConnectPhases(p.Dial, p.TLS, p.Startup)
+	})
+}
+
+// runCompare compares the two exported summaries named in files (a,b) and
+// prints the comparison to stdout
+func runCompare(files string) {
+	a, b, ok := strings.Cut(files, ",")
+	if !ok {
+		fatal("-compare takes two summary files as a.json,b.json")
+	}
+	var runs [2]api.RunMetadata
+	for i, name := range []string{a, b} {
+		raw, err := os.ReadFile(name)
+		if err != nil {
+			fatal("Could not read summary", "error", err)
+		}
+		if err := json.Unmarshal(raw, &runs[i]); err != nil {
+			fatal("Invalid summary", "file", name, "error", err)
+		}
+	}
+
+	comparison, err := api.CompareRuns(runs[0], runs[1])
+	if err != nil {
+		fatal("Comparison failed", "error", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(comparison)
+}
+
+// runFindMaxQPS runs a throughput search with the configured connections and
+// read/write ratio and logs the maximum sustainable QPS, writing it as JUnit
+// XML to junitPath if set. It exits non-zero if that is below minQPS.
+func runFindMaxQPS(controller *load.Controller, cfg *config.Config, hold time.Duration, maxErrorRate float64, minQPS int, junitPath string) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	opts := load.SearchOptions{
+		StartQPS:     max(cfg.DefaultReadQPS+cfg.DefaultWriteQPS, 1),
+		MaxQPS:       cfg.MaxReadQPS + cfg.MaxWriteQPS,
+		Hold:         hold,
+		MinAchieved:  0.95,
+		MaxErrorRate: maxErrorRate,
+	}
+	slog.Info("Searching for max QPS", "connections", cfg.DefaultConnections, "hold", hold)
+
+	result, err := controller.FindMaxQPS(ctx, opts, func(step load.SearchStep) {
+		slog.Info("Search step", "step", step.String())
+	})
+	if err != nil {
+		fatal("QPS search failed", "error", err)
+	}
+	slog.Info("Max sustainable QPS", "qps", result.MaxQPS)
+
+	if junitPath != "" {
+		f, err := os.Create(junitPath)
+		if err != nil {
+			fatal("Could not create JUnit report", "error", err)
+		}
+		err = result.WriteJUnit(f, minQPS)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			fatal("Could not write JUnit report", "error", err)
+		}
+	}
+	if result.MaxQPS < minQPS {
+		fatal("Max sustainable QPS is below the minimum", "qps", result.MaxQPS, "min_qps", minQPS)
 	}
 }
 
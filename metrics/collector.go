@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"maps"
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -14,44 +16,213 @@ type Collector struct {
 	readLatencies  *Histogram
 	writeLatencies *Histogram
 
+	// Connection establishment latencies
+	connectLatencies *Histogram
+
+	// Connect sub-phases: dial, TLS handshake, startup/auth
+	dialLatencies    *Histogram
+	tlsLatencies     *Histogram
+	startupLatencies *Histogram
+
+	// Connection lifetimes from connect to close (cumulative, reset via Reset())
+	connLifetimes *Histogram
+
+	// EXPLAIN ANALYZE sample histograms (planning vs execution)
+	readPlanning   *Histogram
+	readExecution  *Histogram
+	writePlanning  *Histogram
+	writeExecution *Histogram
+
+	// Cross-connection visibility checks: time from first read until the
+	// probed insert was visible, plus checks that timed out
+	visibilityDelays   *Histogram
+	visibilityTimeouts int64
+
+	// Per-endpoint read metrics, keyed by endpoint name. The map is fixed by
+	// RegisterEndpoints before load starts, so lookups need no lock.
+	endpoints map[string]*endpointMetrics
+
 	// Window counters (reset each interval)
-	readCount   int64
-	writeCount  int64
-	readErrors  int64
-	writeErrors int64
+	readCount    int64
+	writeCount   int64
+	readErrors   int64
+	writeErrors  int64
+	writeRetries int64
+	readRetries  int64
+	writeRows    int64 // Rows committed by batched write transactions
+	readRows     int64 // Rows fetched by range reads
+
+	// Chaos mode: connections severed this window, and how long each severed
+	// worker took to get a new connection
+	chaosSevered    int64
+	chaosReconnects *Histogram
+
+	// Reads whose ID came from the recent-insert cache vs a random guess
+	readsCachedID int64
+	readsRandomID int64
+
+	// Operations that waited for a concurrency slot (MaxConcurrentReads/Writes)
+	readConcurrencyWaits  int64
+	writeConcurrencyWaits int64
+
+	// Simulated network latency injected by workers (sum in µs, sample count)
+	readInjectedUs  int64
+	readInjected    int64
+	writeInjectedUs int64
+	writeInjected   int64
 
 	// Total counters (never reset except via Reset())
 	totalQueries atomic.Int64
 	totalErrors  atomic.Int64
+	totalRetries atomic.Int64
+
+	// Estimated bytes of values read and written (never reset except via Reset())
+	totalReadBytes  atomic.Int64
+	totalWriteBytes atomic.Int64
+
+	// Set while a run warms up: reads and writes are discarded until
+	// EndWarmup resets everything
+	warmingUp atomic.Bool
+
+	// Cumulative max latency in ms, stored as float64 bits (reset via Reset())
+	readMaxLatency  atomic.Uint64
+	writeMaxLatency atomic.Uint64
+
+	// Latency bucket counts of the last completed window (guarded by mu)
+	lastReadBuckets  []Bucket
+	lastWriteBuckets []Bucket
+
+	// EWMA-smoothed QPS, updated each snapshot (guarded by mu)
+	qpsAlpha      float64
+	readQPSEWMA   float64
+	writeQPSEWMA  float64
+	qpsEWMAPrimed bool
 
 	// Recent errors list (for UI visibility)
 	recentErrors    []ErrorEntry
 	lastErrorTime   time.Time
 	maxRecentErrors int
-	errorsVersion   int64 // incremented when recentErrors changes
+	errorsVersion   int64         // incremented when recentErrors changes
+	errorsRepeated  bool          // a repeat bumped a count since the last Snapshot
+	errorSampling   time.Duration // minimum time between sampling distinct messages
+
+	// Cumulative error counts by SQLSTATE (guarded by mu, reset via Reset())
+	errorCodes map[string]int64
+
+	// Ring buffer of recent snapshots for /api/history (guarded by mu)
+	history     []MetricsSnapshot
+	historyNext int // ring position of the next snapshot once full
+	historySize int
+	latest      *MetricsSnapshot // Most recent snapshot, kept without history
+
+	// Live stream of distinct errors for /ws/errors
+	errorStream errorStream
 
 	// Pool stats function
 	poolStatsFunc func() PoolStats
 
-	// Start time for uptime calculation
+	// Latest workload table stats from the table monitor (guarded by mu)
+	tableStats *TableStats
+
+	// Latest WAL generation rate from the WAL monitor (guarded by mu)
+	walStats *WALStats
+
+	// Latest target resource usage from PollTarget (guarded by mu)
+	targetStats *TargetStats
+
+	// Start time for uptime calculation (guarded by mu)
 	startTime time.Time
 }
 
 // NewCollector creates a new metrics collector
 func NewCollector(poolStatsFunc func() PoolStats) *Collector {
 	return &Collector{
-		readLatencies:   NewHistogram(),
-		writeLatencies:  NewHistogram(),
-		poolStatsFunc:   poolStatsFunc,
-		startTime:       time.Now(),
-		recentErrors:    make([]ErrorEntry, 0),
-		maxRecentErrors: 10, // Keep last 10 errors
+		readLatencies:    NewHistogram(),
+		writeLatencies:   NewHistogram(),
+		connectLatencies: NewHistogram(),
+		dialLatencies:    NewHistogram(),
+		tlsLatencies:     NewHistogram(),
+		startupLatencies: NewHistogram(),
+		connLifetimes:    NewLifetimeHistogram(),
+		readPlanning:     NewPlanHistogram(),
+		readExecution:    NewPlanHistogram(),
+		writePlanning:    NewPlanHistogram(),
+		writeExecution:   NewPlanHistogram(),
+		visibilityDelays: NewHistogram(),
+		chaosReconnects:  NewHistogram(),
+		poolStatsFunc:    poolStatsFunc,
+		startTime:        time.Now(),
+		recentErrors:     make([]ErrorEntry, 0),
+		maxRecentErrors:  10, // Keep last 10 errors
+		errorSampling:    10 * time.Second,
+		historySize:      defaultHistorySize,
+		qpsAlpha:         defaultQPSAlpha,
+	}
+}
+
+// defaultQPSAlpha is the EWMA weight given to each new QPS sample
+const defaultQPSAlpha = 0.2
+
+// qpsAlphaWindow is the window length the QPS smoothing alpha applies to.
+// Windows of other lengths are weighted with the same time constant, so
+// changing the metrics interval doesn't change how fast the average follows.
+const qpsAlphaWindow = 100 * time.Millisecond
+
+// SetQPSSmoothing sets the EWMA weight (0-1] given to a 100ms window's QPS
+// sample. Lower values are steadier but slower to follow changes.
+func (c *Collector) SetQPSSmoothing(alpha float64) {
+	if alpha <= 0 || alpha > 1 {
+		alpha = defaultQPSAlpha
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.qpsAlpha = alpha
+}
+
+// smoothQPS folds the raw QPS of a window of length interval into the
+// running averages (caller holds c.mu)
+func (c *Collector) smoothQPS(readQPS, writeQPS float64, interval time.Duration) (float64, float64) {
+	if !c.qpsEWMAPrimed {
+		c.readQPSEWMA, c.writeQPSEWMA = readQPS, writeQPS
+		c.qpsEWMAPrimed = true
+	} else {
+		alpha := c.windowAlpha(interval)
+		c.readQPSEWMA += alpha * (readQPS - c.readQPSEWMA)
+		c.writeQPSEWMA += alpha * (writeQPS - c.writeQPSEWMA)
 	}
+	return c.readQPSEWMA, c.writeQPSEWMA
+}
+
+// windowAlpha scales the smoothing alpha to a window of length interval as
+// 1 - exp(-interval/tau), with tau the time constant alpha gives a
+// qpsAlphaWindow window (caller holds c.mu)
+func (c *Collector) windowAlpha(interval time.Duration) float64 {
+	if c.qpsAlpha >= 1 {
+		return 1
+	}
+	tau := -qpsAlphaWindow.Seconds() / math.Log(1-c.qpsAlpha)
+	return 1 - math.Exp(-interval.Seconds()/tau)
 }
 
 // RecordRead records a read operation
 func (c *Collector) RecordRead(latency time.Duration, err error) {
+	if c.warmingUp.Load() {
+		return
+	}
 	c.readLatencies.Record(latency)
+	c.countRead(err)
+}
+
+// RecordExplainedRead records a read run under EXPLAIN ANALYZE, counting it
+// without adding its latency, inflated by the instrumentation, to the histogram
+func (c *Collector) RecordExplainedRead(err error) {
+	if c.warmingUp.Load() {
+		return
+	}
+	c.countRead(err)
+}
+
+func (c *Collector) countRead(err error) {
 	atomic.AddInt64(&c.readCount, 1)
 	c.totalQueries.Add(1)
 
@@ -59,12 +230,30 @@ func (c *Collector) RecordRead(latency time.Duration, err error) {
 		atomic.AddInt64(&c.readErrors, 1)
 		c.totalErrors.Add(1)
 		c.addError("read: " + err.Error())
+		c.countErrorCode(err)
+		c.errorStream.publish("read", err)
 	}
 }
 
 // RecordWrite records a write operation
 func (c *Collector) RecordWrite(latency time.Duration, err error) {
+	if c.warmingUp.Load() {
+		return
+	}
 	c.writeLatencies.Record(latency)
+	c.countWrite(err)
+}
+
+// RecordExplainedWrite records a write run under EXPLAIN ANALYZE, counting it
+// without adding its latency to the histogram
+func (c *Collector) RecordExplainedWrite(err error) {
+	if c.warmingUp.Load() {
+		return
+	}
+	c.countWrite(err)
+}
+
+func (c *Collector) countWrite(err error) {
 	atomic.AddInt64(&c.writeCount, 1)
 	c.totalQueries.Add(1)
 
@@ -72,16 +261,226 @@ func (c *Collector) RecordWrite(latency time.Duration, err error) {
 		atomic.AddInt64(&c.writeErrors, 1)
 		c.totalErrors.Add(1)
 		c.addError("write: " + err.Error())
+		c.countErrorCode(err)
+		c.errorStream.publish("write", err)
+	}
+}
+
+// RecordConnect records how long establishing a connection took
+func (c *Collector) RecordConnect(latency time.Duration) {
+	c.connectLatencies.Record(latency)
+}
+
+// RecordConnectPhases records the sub-phases of one connect; tls is zero when
+// the connection was not encrypted
+func (c *Collector) RecordConnectPhases(dial, tls, startup time.Duration) {
+	c.dialLatencies.Record(dial)
+	if tls > 0 {
+		c.tlsLatencies.Record(tls)
+	}
+	c.startupLatencies.Record(startup)
+}
+
+// RecordChaosSevered records connections force-closed by one chaos event
+func (c *Collector) RecordChaosSevered(n int) {
+	atomic.AddInt64(&c.chaosSevered, int64(n))
+}
+
+// RecordChaosReconnect records how long a worker took to reconnect after
+// chaos severed its connection
+func (c *Collector) RecordChaosReconnect(d time.Duration) {
+	c.chaosReconnects.Record(d)
+}
+
+// RecordConnectionLifetime records how long a connection stayed open before closing
+func (c *Collector) RecordConnectionLifetime(lifetime time.Duration) {
+	c.connLifetimes.Record(lifetime)
+}
+
+// RecordReadInjected records simulated network latency added before a read
+func (c *Collector) RecordReadInjected(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	atomic.AddInt64(&c.readInjectedUs, d.Microseconds())
+	atomic.AddInt64(&c.readInjected, 1)
+}
+
+// RecordWriteInjected records simulated network latency added before a write
+func (c *Collector) RecordWriteInjected(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	atomic.AddInt64(&c.writeInjectedUs, d.Microseconds())
+	atomic.AddInt64(&c.writeInjected, 1)
+}
+
+// RecordWriteRetry records a write attempt that failed transiently and was retried
+func (c *Collector) RecordWriteRetry() {
+	atomic.AddInt64(&c.writeRetries, 1)
+	c.totalRetries.Add(1)
+}
+
+// RecordReadBytes adds the estimated size of values returned by a read
+func (c *Collector) RecordReadBytes(n int) {
+	c.totalReadBytes.Add(int64(n))
+}
+
+// RecordWriteBytes adds the estimated size of values sent by a write
+func (c *Collector) RecordWriteBytes(n int) {
+	c.totalWriteBytes.Add(int64(n))
+}
+
+// RecordReadRetry records a read that failed on a broken connection and is
+// being retried on a fresh one
+func (c *Collector) RecordReadRetry() {
+	atomic.AddInt64(&c.readRetries, 1)
+	c.totalRetries.Add(1)
+}
+
+// RecordReadID records whether a read's ID came from the recent-insert cache
+// or was guessed at random
+func (c *Collector) RecordReadID(cached bool) {
+	if cached {
+		atomic.AddInt64(&c.readsCachedID, 1)
+	} else {
+		atomic.AddInt64(&c.readsRandomID, 1)
+	}
+}
+
+// RecordReadConcurrencyWait records a read that waited for a concurrency slot
+func (c *Collector) RecordReadConcurrencyWait() {
+	atomic.AddInt64(&c.readConcurrencyWaits, 1)
+}
+
+// RecordWriteConcurrencyWait records a write that waited for a concurrency slot
+func (c *Collector) RecordWriteConcurrencyWait() {
+	atomic.AddInt64(&c.writeConcurrencyWaits, 1)
+}
+
+// RecordWriteRows records rows committed by a batched write transaction
+func (c *Collector) RecordWriteRows(n int) {
+	atomic.AddInt64(&c.writeRows, int64(n))
+}
+
+// RecordReadRows records rows fetched by a range read
+func (c *Collector) RecordReadRows(n int) {
+	atomic.AddInt64(&c.readRows, int64(n))
+}
+
+// RecordReadPlan records server-side planning and execution time for a sampled read
+func (c *Collector) RecordReadPlan(planning, execution time.Duration) {
+	c.readPlanning.Record(planning)
+	c.readExecution.Record(execution)
+}
+
+// RecordWritePlan records server-side planning and execution time for a sampled write
+func (c *Collector) RecordWritePlan(planning, execution time.Duration) {
+	c.writePlanning.Record(planning)
+	c.writeExecution.Record(execution)
+}
+
+// RecordVisibility records a cross-connection visibility check: how long the
+// reader polled before the insert became visible, or that it never did
+func (c *Collector) RecordVisibility(delay time.Duration, visible bool) {
+	if !visible {
+		atomic.AddInt64(&c.visibilityTimeouts, 1)
+		return
+	}
+	c.visibilityDelays.Record(delay)
+}
+
+// endpointMetrics holds window read metrics for one named endpoint
+type endpointMetrics struct {
+	latencies *Histogram
+	errors    atomic.Int64
+}
+
+// RegisterEndpoints sets up per-endpoint read metrics. Call before starting
+// load; reads against unregistered names are ignored.
+func (c *Collector) RegisterEndpoints(names []string) {
+	c.endpoints = make(map[string]*endpointMetrics, len(names))
+	for _, name := range names {
+		c.endpoints[name] = &endpointMetrics{latencies: NewHistogram()}
+	}
+}
+
+// RecordEndpointRead records a read against a named endpoint (in addition to RecordRead)
+func (c *Collector) RecordEndpointRead(name string, latency time.Duration, err error) {
+	m, ok := c.endpoints[name]
+	if !ok {
+		return
+	}
+	m.latencies.Record(latency)
+	if err != nil {
+		m.errors.Add(1)
+	}
+}
+
+// endpointSnapshot returns and resets per-endpoint read stats, or nil if no
+// endpoints are registered
+func (c *Collector) endpointSnapshot(intervalSec float64) map[string]OperationStats {
+	if len(c.endpoints) == 0 {
+		return nil
+	}
+	stats := make(map[string]OperationStats, len(c.endpoints))
+	for name, m := range c.endpoints {
+		hist := m.latencies.SnapshotAndReset()
+		stats[name] = OperationStats{
+			QPS:        float64(hist.Count) / intervalSec,
+			LatencyP50: hist.P50,
+			LatencyP90: hist.P90,
+			LatencyP95: hist.P95,
+			LatencyP99: hist.P99,
+			LatencyAvg: hist.Avg,
+			LatencyMax: hist.Max,
+			Errors:     m.errors.Swap(0),
+		}
 	}
+	return stats
 }
 
-// addError adds an error to the recent errors list (rate limited to 1 per 10 seconds)
+// countErrorCode tallies err by its SQLSTATE, or under noSQLState for errors
+// that never reached the server (dial failures, timeouts, closed connections)
+func (c *Collector) countErrorCode(err error) {
+	code := sqlState(err)
+	if code == "" {
+		code = noSQLState
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.errorCodes == nil {
+		c.errorCodes = make(map[string]int64)
+	}
+	c.errorCodes[code]++
+}
+
+// SetRecentErrors sets how many recent errors are kept and the minimum time
+// between sampling distinct messages into the list (0 keeps every one)
+func (c *Collector) SetRecentErrors(maxErrors int, sampling time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxRecentErrors = max(maxErrors, 1)
+	c.errorSampling = max(sampling, 0)
+}
+
+// addError adds an error to the recent errors list. A repeat of the newest
+// message is folded into its count; other messages are sampled at most once
+// per errorSampling.
 func (c *Collector) addError(errMsg string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Rate limit: only add 1 error per 10 seconds
-	if time.Since(c.lastErrorTime) < 10*time.Second {
+	// Repeats only bump the version once per Snapshot, so a flood of one
+	// error doesn't resend the list with every window's count change
+	if n := len(c.recentErrors); n > 0 && c.recentErrors[n-1].Message == errMsg {
+		c.recentErrors[n-1].Count++
+		c.errorsRepeated = true
+		return
+	}
+
+	if time.Since(c.lastErrorTime) < c.errorSampling {
 		return
 	}
 	c.lastErrorTime = time.Now()
@@ -90,6 +489,7 @@ func (c *Collector) addError(errMsg string) {
 	entry := ErrorEntry{
 		Timestamp: time.Now().UnixMilli(),
 		Message:   errMsg,
+		Count:     1,
 	}
 	c.recentErrors = append(c.recentErrors, entry)
 
@@ -106,28 +506,53 @@ func (c *Collector) Snapshot(interval time.Duration, lastErrorsVersion int64) Me
 	// Get histogram snapshots (this also resets them)
 	readHist := c.readLatencies.SnapshotAndReset()
 	writeHist := c.writeLatencies.SnapshotAndReset()
+	explain := c.explainSnapshot()
+	visibility := c.visibilitySnapshot()
+	chaos := c.chaosSnapshot()
+	connectHist := c.connectLatencies.SnapshotAndReset()
+	connectPhases := c.connectPhaseSnapshot()
+	readMax := updateMax(&c.readMaxLatency, readHist.Max)
+	writeMax := updateMax(&c.writeMaxLatency, writeHist.Max)
 
 	// Get and reset window counters
 	readCount := atomic.SwapInt64(&c.readCount, 0)
 	writeCount := atomic.SwapInt64(&c.writeCount, 0)
 	readErrors := atomic.SwapInt64(&c.readErrors, 0)
 	writeErrors := atomic.SwapInt64(&c.writeErrors, 0)
+	writeRetries := atomic.SwapInt64(&c.writeRetries, 0)
+	readRetries := atomic.SwapInt64(&c.readRetries, 0)
+	writeRows := atomic.SwapInt64(&c.writeRows, 0)
+	readRows := atomic.SwapInt64(&c.readRows, 0)
+	readConcurrencyWaits := atomic.SwapInt64(&c.readConcurrencyWaits, 0)
+	readsCachedID := atomic.SwapInt64(&c.readsCachedID, 0)
+	readsRandomID := atomic.SwapInt64(&c.readsRandomID, 0)
+	writeConcurrencyWaits := atomic.SwapInt64(&c.writeConcurrencyWaits, 0)
+	readInjectedAvg := swapAverageMs(&c.readInjectedUs, &c.readInjected)
+	writeInjectedAvg := swapAverageMs(&c.writeInjectedUs, &c.writeInjected)
 
 	// Calculate QPS based on actual interval
 	intervalSec := interval.Seconds()
 	readQPS := float64(readCount) / intervalSec
 	writeQPS := float64(writeCount) / intervalSec
 
-	// Get totals
-	totalQueries := c.totalQueries.Load()
-	totalErrors := c.totalErrors.Load()
+	endpoints := c.endpointSnapshot(intervalSec)
 
-	// Calculate error rate
-	var errorRate float64
-	if totalQueries > 0 {
-		errorRate = float64(totalErrors) / float64(totalQueries)
+	// Connection stats are only reported when connections were opened this window
+	var connect *OperationStats
+	if connectHist.Count > 0 {
+		connect = &OperationStats{
+			QPS:        float64(connectHist.Count) / intervalSec,
+			LatencyP50: connectHist.P50,
+			LatencyP90: connectHist.P90,
+			LatencyP95: connectHist.P95,
+			LatencyP99: connectHist.P99,
+			LatencyAvg: connectHist.Avg,
+			LatencyMax: connectHist.Max,
+		}
 	}
 
+	totals := c.Totals()
+
 	// Get pool stats
 	var poolStats PoolStats
 	if c.poolStatsFunc != nil {
@@ -136,40 +561,267 @@ func (c *Collector) Snapshot(interval time.Duration, lastErrorsVersion int64) Me
 
 	// Only include recent errors if they've changed since caller last saw them
 	var recentErrors []ErrorEntry
-	c.mu.RLock()
+	c.mu.Lock()
+	c.lastReadBuckets = c.readLatencies.bucketsOf(readHist)
+	c.lastWriteBuckets = c.writeLatencies.bucketsOf(writeHist)
+	readQPSSmoothed, writeQPSSmoothed := c.smoothQPS(readQPS, writeQPS, interval)
+	if c.errorsRepeated {
+		c.errorsVersion++
+		c.errorsRepeated = false
+	}
 	currentVersion := c.errorsVersion
 	if currentVersion != lastErrorsVersion {
 		recentErrors = make([]ErrorEntry, len(c.recentErrors))
 		copy(recentErrors, c.recentErrors)
 	}
-	c.mu.RUnlock()
+	var errorCodes map[string]int64
+	if len(c.errorCodes) > 0 {
+		errorCodes = make(map[string]int64, len(c.errorCodes))
+		maps.Copy(errorCodes, c.errorCodes)
+	}
+	tableStats := c.tableStats
+	walStats := c.walStats
+	targetStats := c.targetStats
+	c.mu.Unlock()
 
-	return MetricsSnapshot{
+	snapshot := MetricsSnapshot{
 		Timestamp: time.Now().UnixMilli(),
+		WarmingUp: c.warmingUp.Load(),
 		Reads: OperationStats{
 			QPS:        readQPS,
 			LatencyP50: readHist.P50,
+			LatencyP90: readHist.P90,
+			LatencyP95: readHist.P95,
 			LatencyP99: readHist.P99,
 			LatencyAvg: readHist.Avg,
+			LatencyMax: readHist.Max,
 			Errors:     readErrors,
+			Retries:    readRetries,
+
+			QPSSmoothed:          readQPSSmoothed,
+			RowsPerSec:           float64(readRows) / intervalSec,
+			ConcurrencyWaits:     readConcurrencyWaits,
+			CachedIDReads:        readsCachedID,
+			RandomIDReads:        readsRandomID,
+			LatencyMaxCumulative: readMax,
+			InjectedLatencyAvg:   readInjectedAvg,
 		},
 		Writes: OperationStats{
 			QPS:        writeQPS,
 			LatencyP50: writeHist.P50,
+			LatencyP90: writeHist.P90,
+			LatencyP95: writeHist.P95,
 			LatencyP99: writeHist.P99,
 			LatencyAvg: writeHist.Avg,
+			LatencyMax: writeHist.Max,
 			Errors:     writeErrors,
+			Retries:    writeRetries,
+
+			QPSSmoothed:          writeQPSSmoothed,
+			RowsPerSec:           float64(writeRows) / intervalSec,
+			ConcurrencyWaits:     writeConcurrencyWaits,
+			LatencyMaxCumulative: writeMax,
+			InjectedLatencyAvg:   writeInjectedAvg,
 		},
-		Totals: TotalStats{
-			Queries:   totalQueries,
-			Errors:    totalErrors,
-			ErrorRate: errorRate,
-		},
-		Pool:         poolStats,
-		RecentErrors: recentErrors,
+		Totals:             totals,
+		Pool:               poolStats,
+		Connect:            connect,
+		ConnectPhases:      connectPhases,
+		ConnectionLifetime: c.ConnectionLifetime(),
+		Explain:            explain,
+		Visibility:         visibility,
+		Chaos:              chaos,
+		Endpoints:          endpoints,
+		Table:              tableStats,
+		WAL:                walStats,
+		Target:             targetStats,
+		RecentErrors:       recentErrors,
+		ErrorsByCode:       errorCodes,
+	}
+	c.appendHistory(snapshot)
+	return snapshot
+}
+
+// swapAverageMs resets a µs sum and sample count, returning their average in ms
+func swapAverageMs(sumUs, count *int64) float64 {
+	sum := atomic.SwapInt64(sumUs, 0)
+	n := atomic.SwapInt64(count, 0)
+	if n == 0 {
+		return 0
+	}
+	return float64(sum) / float64(n) / 1000.0
+}
+
+// updateMax raises the cumulative max stored in m to v if larger and returns the result
+func updateMax(m *atomic.Uint64, v float64) float64 {
+	for {
+		bits := m.Load()
+		cur := math.Float64frombits(bits)
+		if v <= cur {
+			return cur
+		}
+		if m.CompareAndSwap(bits, math.Float64bits(v)) {
+			return v
+		}
+	}
+}
+
+// explainSnapshot returns planning/execution stats for the window, or nil
+// if no queries were sampled
+func (c *Collector) explainSnapshot() *ExplainStats {
+	readPlan := c.readPlanning.SnapshotAndReset()
+	readExec := c.readExecution.SnapshotAndReset()
+	writePlan := c.writePlanning.SnapshotAndReset()
+	writeExec := c.writeExecution.SnapshotAndReset()
+
+	if readPlan.Count == 0 && writePlan.Count == 0 {
+		return nil
+	}
+
+	return &ExplainStats{
+		Reads:  newPlanStats(readPlan, readExec),
+		Writes: newPlanStats(writePlan, writeExec),
+	}
+}
+
+// visibilitySnapshot returns the window's visibility check stats, or nil if
+// no checks completed
+func (c *Collector) visibilitySnapshot() *VisibilityStats {
+	delays := c.visibilityDelays.SnapshotAndReset()
+	timeouts := atomic.SwapInt64(&c.visibilityTimeouts, 0)
+	if delays.Count == 0 && timeouts == 0 {
+		return nil
+	}
+	return &VisibilityStats{
+		Checks:   int64(delays.Count) + timeouts,
+		TimedOut: timeouts,
+		DelayP50: delays.P50,
+		DelayP99: delays.P99,
+		DelayMax: delays.Max,
+	}
+}
+
+// connectPhaseSnapshot returns and resets the connect sub-phase latencies, or
+// nil if no timed connect completed this window
+func (c *Collector) connectPhaseSnapshot() *ConnectPhaseStats {
+	dial := c.dialLatencies.SnapshotAndReset()
+	tls := c.tlsLatencies.SnapshotAndReset()
+	startup := c.startupLatencies.SnapshotAndReset()
+	if dial.Count == 0 {
+		return nil
+	}
+	stats := &ConnectPhaseStats{
+		Dial:    newPhaseStats(dial),
+		Startup: newPhaseStats(startup),
+	}
+	if tls.Count > 0 {
+		phase := newPhaseStats(tls)
+		stats.TLS = &phase
+	}
+	return stats
+}
+
+func newPhaseStats(hist HistogramSnapshot) PhaseStats {
+	return PhaseStats{
+		LatencyP50: hist.P50,
+		LatencyP99: hist.P99,
+		LatencyAvg: hist.Avg,
+		LatencyMax: hist.Max,
+	}
+}
+
+func (c *Collector) chaosSnapshot() *ChaosStats {
+	reconnects := c.chaosReconnects.SnapshotAndReset()
+	severed := atomic.SwapInt64(&c.chaosSevered, 0)
+	if severed == 0 && reconnects.Count == 0 {
+		return nil
+	}
+	return &ChaosStats{
+		Severed:      severed,
+		Reconnected:  int64(reconnects.Count),
+		ReconnectP50: reconnects.P50,
+		ReconnectP99: reconnects.P99,
+		ReconnectMax: reconnects.Max,
+	}
+}
+
+func newPlanStats(planning, execution HistogramSnapshot) PlanStats {
+	return PlanStats{
+		Samples:      planning.Count,
+		PlanningAvg:  planning.Avg,
+		PlanningP99:  planning.P99,
+		ExecutionAvg: execution.Avg,
+		ExecutionP99: execution.P99,
+	}
+}
+
+// LatencyBuckets returns the read and write latency histograms' bucket counts
+// for the last window Snapshot completed, so they cover a whole interval
+// rather than however much of the live one has elapsed. Before the first
+// Snapshot every count is zero.
+func (c *Collector) LatencyBuckets() (reads, writes []Bucket) {
+	c.mu.RLock()
+	reads, writes = c.lastReadBuckets, c.lastWriteBuckets
+	c.mu.RUnlock()
+	if reads == nil {
+		reads = c.readLatencies.bucketsOf(HistogramSnapshot{})
+		writes = c.writeLatencies.bucketsOf(HistogramSnapshot{})
+	}
+	return reads, writes
+}
+
+// ConnectionLifetime returns cumulative connection lifetime percentiles,
+// or nil if no connection has closed yet
+func (c *Collector) ConnectionLifetime() *LifetimeStats {
+	hist := c.connLifetimes.Snapshot()
+	if hist.Count == 0 {
+		return nil
+	}
+	return &LifetimeStats{
+		Count:  hist.Count,
+		P50Sec: hist.P50 / 1000.0,
+		P99Sec: hist.P99 / 1000.0,
+		AvgSec: hist.Avg / 1000.0,
+		MaxSec: hist.Max / 1000.0,
+	}
+}
+
+// Totals returns the cumulative counters without resetting any window state
+func (c *Collector) Totals() TotalStats {
+	totalQueries := c.totalQueries.Load()
+	totalErrors := c.totalErrors.Load()
+
+	// Calculate error rate
+	var errorRate float64
+	if totalQueries > 0 {
+		errorRate = float64(totalErrors) / float64(totalQueries)
+	}
+
+	return TotalStats{
+		Queries:   totalQueries,
+		Errors:    totalErrors,
+		ErrorRate: errorRate,
+		Retries:   c.totalRetries.Load(),
+
+		ReadBytes:  c.totalReadBytes.Load(),
+		WriteBytes: c.totalWriteBytes.Load(),
 	}
 }
 
+// SetTableStats stores the latest workload table stats for inclusion in snapshots
+func (c *Collector) SetTableStats(stats TableStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tableStats = &stats
+}
+
+// SetWALStats stores the latest WAL generation rate for inclusion in snapshots
+func (c *Collector) SetWALStats(stats WALStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.walStats = &stats
+}
+
 // ErrorsVersion returns the current errors version counter.
 func (c *Collector) ErrorsVersion() int64 {
 	c.mu.RLock()
@@ -179,25 +831,81 @@ func (c *Collector) ErrorsVersion() int64 {
 
 // Reset clears all metrics
 func (c *Collector) Reset() {
+	c.resetCounters()
+
+	// Clear recent errors and history
+	c.mu.Lock()
+	c.lastReadBuckets = nil
+	c.lastWriteBuckets = nil
+	c.recentErrors = make([]ErrorEntry, 0)
+	c.lastErrorTime = time.Time{}
+	c.errorsVersion++
+	c.errorsRepeated = false
+	c.errorCodes = nil
+	c.history = nil
+	c.historyNext = 0
+	c.latest = nil
+	c.mu.Unlock()
+}
+
+// resetCounters clears the window and cumulative counters and histograms,
+// restarts uptime and the QPS averages, and leaves errors and history alone
+func (c *Collector) resetCounters() {
 	c.readLatencies.SnapshotAndReset()
 	c.writeLatencies.SnapshotAndReset()
+	c.explainSnapshot()
+	c.visibilitySnapshot()
+	c.chaosSnapshot()
+	c.endpointSnapshot(1)
+	c.connectLatencies.SnapshotAndReset()
+	c.connectPhaseSnapshot()
+	c.connLifetimes.SnapshotAndReset()
 	atomic.StoreInt64(&c.readCount, 0)
 	atomic.StoreInt64(&c.writeCount, 0)
 	atomic.StoreInt64(&c.readErrors, 0)
 	atomic.StoreInt64(&c.writeErrors, 0)
+	atomic.StoreInt64(&c.writeRetries, 0)
+	atomic.StoreInt64(&c.readRetries, 0)
+	atomic.StoreInt64(&c.writeRows, 0)
+	atomic.StoreInt64(&c.readRows, 0)
+	atomic.StoreInt64(&c.readConcurrencyWaits, 0)
+	atomic.StoreInt64(&c.readsCachedID, 0)
+	atomic.StoreInt64(&c.readsRandomID, 0)
+	atomic.StoreInt64(&c.writeConcurrencyWaits, 0)
+	swapAverageMs(&c.readInjectedUs, &c.readInjected)
+	swapAverageMs(&c.writeInjectedUs, &c.writeInjected)
 	c.totalQueries.Store(0)
 	c.totalErrors.Store(0)
-	c.startTime = time.Now()
+	c.totalRetries.Store(0)
+	c.totalReadBytes.Store(0)
+	c.totalWriteBytes.Store(0)
+	c.readMaxLatency.Store(0)
+	c.writeMaxLatency.Store(0)
 
-	// Clear recent errors
 	c.mu.Lock()
-	c.recentErrors = make([]ErrorEntry, 0)
-	c.lastErrorTime = time.Time{}
-	c.errorsVersion++
+	c.startTime = time.Now()
+	c.readQPSEWMA, c.writeQPSEWMA = 0, 0
+	c.qpsEWMAPrimed = false
 	c.mu.Unlock()
 }
 
+// BeginWarmup starts discarding reads and writes, so connection setup and
+// cold caches at the start of a run don't skew its results
+func (c *Collector) BeginWarmup() {
+	c.warmingUp.Store(true)
+}
+
+// EndWarmup resets counters and histograms and resumes recording, so the
+// run's totals and percentiles start clean from here. History and recent
+// errors are kept.
+func (c *Collector) EndWarmup() {
+	c.resetCounters()
+	c.warmingUp.Store(false)
+}
+
 // Uptime returns the duration since the collector was created or reset
 func (c *Collector) Uptime() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return time.Since(c.startTime)
 }
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// errorStreamDedupeWindow suppresses repeats of the same operation and
+	// message on the error stream for this long
+	errorStreamDedupeWindow = time.Second
+
+	// errorStreamBuffer is each subscriber's backlog; events are dropped for
+	// subscribers that fall this far behind
+	errorStreamBuffer = 256
+
+	// maxTrackedErrors bounds the dedupe table before it is cleared
+	maxTrackedErrors = 1000
+
+	// noSQLState keys error counts for errors without a server SQLSTATE
+	noSQLState = "none"
+)
+
+// ErrorEvent is a single error published on the error stream
+type ErrorEvent struct {
+	Timestamp int64  `json:"timestamp"`
+	Operation string `json:"operation"`
+	SQLState  string `json:"sqlstate,omitempty"`
+	Message   string `json:"message"`
+}
+
+// errorStream fans out distinct errors to live subscribers as they happen,
+// independent of the snapshot's rate-limited recent errors
+type errorStream struct {
+	mu       sync.Mutex
+	subs     map[chan ErrorEvent]struct{}
+	lastSeen map[string]time.Time
+}
+
+// SubscribeErrors returns a channel receiving distinct errors as they occur
+// and a function to unsubscribe (which closes the channel)
+func (c *Collector) SubscribeErrors() (<-chan ErrorEvent, func()) {
+	s := &c.errorStream
+	ch := make(chan ErrorEvent, errorStreamBuffer)
+
+	s.mu.Lock()
+	if s.subs == nil {
+		s.subs = make(map[chan ErrorEvent]struct{})
+	}
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subs[ch]; ok {
+			delete(s.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// publish sends err to subscribers unless the same error was sent within
+// errorStreamDedupeWindow. It never blocks.
+func (s *errorStream) publish(op string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.subs) == 0 {
+		return
+	}
+
+	now := time.Now()
+	msg := err.Error()
+	key := op + ": " + msg
+	if last, ok := s.lastSeen[key]; ok && now.Sub(last) < errorStreamDedupeWindow {
+		return
+	}
+	if s.lastSeen == nil || len(s.lastSeen) >= maxTrackedErrors {
+		s.lastSeen = make(map[string]time.Time)
+	}
+	s.lastSeen[key] = now
+
+	event := ErrorEvent{
+		Timestamp: now.UnixMilli(),
+		Operation: op,
+		SQLState:  sqlState(err),
+		Message:   msg,
+	}
+	for ch := range s.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// sqlState extracts the SQLSTATE code from a server error, if err carries one
+func sqlState(err error) string {
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState()
+	}
+	return ""
+}
@@ -8,8 +8,8 @@ import (
 // Bucket upper bounds in microseconds.
 // Chosen for good resolution across typical DB query latencies (0.1ms–5s).
 // The final bucket catches everything above the last bound.
-var bucketBoundsUs = [numBounds]int64{
-	100, 250, 500, 750,         // 0.1ms – 0.75ms
+var bucketBoundsUs = []int64{
+	100, 250, 500, 750, // 0.1ms – 0.75ms
 	1_000, 1_500, 2_000, 3_000, // 1ms – 3ms
 	5_000, 7_500, 10_000, 15_000, // 5ms – 15ms
 	20_000, 30_000, 50_000, 75_000, // 20ms – 75ms
@@ -17,27 +17,74 @@ var bucketBoundsUs = [numBounds]int64{
 	1_000_000, 5_000_000, // 1s – 5s
 }
 
-const numBounds = 21
-const numBuckets = numBounds + 1
+// Lifetime bucket upper bounds in microseconds, for connection lifetimes (0.1s–1h).
+var lifetimeBoundsUs = []int64{
+	100_000, 250_000, 500_000, // 0.1s – 0.5s
+	1_000_000, 2_000_000, 5_000_000, // 1s – 5s
+	10_000_000, 20_000_000, 30_000_000, // 10s – 30s
+	60_000_000, 120_000_000, 300_000_000, // 1m – 5m
+	600_000_000, 1_800_000_000, 3_600_000_000, // 10m – 1h
+}
+
+// Plan bucket upper bounds in microseconds, for server-side planning and
+// execution times (10µs–50ms), which mostly fall under the first query bucket.
+var planBoundsUs = []int64{
+	10, 20, 30, 50, 75, // 10µs – 75µs
+	100, 150, 200, 300, 500, 750, // 0.1ms – 0.75ms
+	1_000, 2_000, 5_000, 10_000, 50_000, // 1ms – 50ms
+}
 
 // Histogram collects latency samples in pre-defined buckets using atomic counters.
 // Record is completely lock-free.
 type Histogram struct {
-	buckets [numBuckets]atomic.Int64
-	sum     atomic.Int64 // total latency in microseconds
+	bounds  []int64        // bucket upper bounds in microseconds
+	buckets []atomic.Int64 // len(bounds)+1; the last bucket is overflow
+	sum     atomic.Int64   // total latency in microseconds
+	max     atomic.Int64   // largest latency in microseconds
 }
 
 // HistogramSnapshot holds computed percentiles from a histogram window.
 type HistogramSnapshot struct {
 	P50   float64
+	P90   float64
+	P95   float64
 	P99   float64
 	Avg   float64
+	Max   float64
 	Count int
+
+	counts []int64 // Per-bucket counts, for Histogram.bucketsOf
 }
 
-// NewHistogram creates a new histogram.
+// Bucket is one histogram bucket's count of samples at or above LowerMs and
+// below UpperMs. UpperMs is omitted for the final, unbounded bucket.
+type Bucket struct {
+	LowerMs float64 `json:"lower_ms"`
+	UpperMs float64 `json:"upper_ms,omitempty"`
+	Count   int64   `json:"count"`
+}
+
+// NewHistogram creates a new histogram with bounds suited to query latencies.
 func NewHistogram() *Histogram {
-	return &Histogram{}
+	return newHistogramWithBounds(bucketBoundsUs)
+}
+
+// NewLifetimeHistogram creates a histogram with bounds suited to connection lifetimes.
+func NewLifetimeHistogram() *Histogram {
+	return newHistogramWithBounds(lifetimeBoundsUs)
+}
+
+// NewPlanHistogram creates a histogram with bounds suited to server-side
+// planning and execution times.
+func NewPlanHistogram() *Histogram {
+	return newHistogramWithBounds(planBoundsUs)
+}
+
+func newHistogramWithBounds(bounds []int64) *Histogram {
+	return &Histogram{
+		bounds:  bounds,
+		buckets: make([]atomic.Int64, len(bounds)+1),
+	}
 }
 
 // Record adds a latency sample. Lock-free — uses only atomic operations.
@@ -45,9 +92,9 @@ func (h *Histogram) Record(d time.Duration) {
 	us := d.Microseconds()
 
 	// Linear scan — fast for the common case (low-latency queries hit early buckets)
-	idx := numBounds // overflow bucket
-	for i := 0; i < numBounds; i++ {
-		if us < bucketBoundsUs[i] {
+	idx := len(h.bounds) // overflow bucket
+	for i, bound := range h.bounds {
+		if us < bound {
 			idx = i
 			break
 		}
@@ -55,44 +102,100 @@ func (h *Histogram) Record(d time.Duration) {
 
 	h.buckets[idx].Add(1)
 	h.sum.Add(us)
+
+	// Track the window maximum with a CAS loop (still lock-free)
+	for {
+		cur := h.max.Load()
+		if us <= cur || h.max.CompareAndSwap(cur, us) {
+			break
+		}
+	}
 }
 
 // SnapshotAndReset reads all bucket counts, computes percentiles, and resets.
 func (h *Histogram) SnapshotAndReset() HistogramSnapshot {
-	// Swap all counters to zero and read their values.
+	return h.snapshot(true)
+}
+
+// Snapshot reads all bucket counts and computes percentiles without resetting.
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	return h.snapshot(false)
+}
+
+// Buckets returns the raw count in every bucket, without resetting, for
+// seeing the shape of the distribution that percentiles hide.
+func (h *Histogram) Buckets() []Bucket {
+	return h.bucketsOf(h.Snapshot())
+}
+
+// bucketsOf labels the counts captured in s, which must have been taken
+// from h, with h's bucket bounds
+func (h *Histogram) bucketsOf(s HistogramSnapshot) []Bucket {
+	buckets := make([]Bucket, len(h.buckets))
+	for i := range buckets {
+		buckets[i] = Bucket{LowerMs: float64(h.bucketLowerUs(i)) / 1000.0}
+		if s.counts != nil {
+			buckets[i].Count = s.counts[i]
+		}
+		if i < len(h.bounds) {
+			buckets[i].UpperMs = float64(h.bounds[i]) / 1000.0
+		}
+	}
+	return buckets
+}
+
+func (h *Histogram) snapshot(reset bool) HistogramSnapshot {
+	// Read (and optionally swap to zero) all counters.
 	// Not perfectly atomic across all buckets, but the error is bounded
 	// to samples recorded during the few nanoseconds of the swap loop.
-	var counts [numBuckets]int64
+	counts := make([]int64, len(h.buckets))
 	var totalCount int64
 	for i := range counts {
-		counts[i] = h.buckets[i].Swap(0)
+		if reset {
+			counts[i] = h.buckets[i].Swap(0)
+		} else {
+			counts[i] = h.buckets[i].Load()
+		}
 		totalCount += counts[i]
 	}
-	totalSum := h.sum.Swap(0)
+
+	var totalSum, maxUs int64
+	if reset {
+		totalSum = h.sum.Swap(0)
+		maxUs = h.max.Swap(0)
+	} else {
+		totalSum = h.sum.Load()
+		maxUs = h.max.Load()
+	}
 
 	if totalCount == 0 {
-		return HistogramSnapshot{}
+		return HistogramSnapshot{counts: counts}
 	}
 
 	return HistogramSnapshot{
-		P50:   percentileFromBuckets(counts[:], totalCount, 0.50),
-		P99:   percentileFromBuckets(counts[:], totalCount, 0.99),
+		P50:   h.percentileFromBuckets(counts, totalCount, 0.50),
+		P90:   h.percentileFromBuckets(counts, totalCount, 0.90),
+		P95:   h.percentileFromBuckets(counts, totalCount, 0.95),
+		P99:   h.percentileFromBuckets(counts, totalCount, 0.99),
 		Avg:   float64(totalSum) / float64(totalCount) / 1000.0, // µs → ms
+		Max:   float64(maxUs) / 1000.0,
 		Count: int(totalCount),
+
+		counts: counts,
 	}
 }
 
 // percentileFromBuckets estimates a percentile by linear interpolation
 // within the bucket that contains the target rank.
-func percentileFromBuckets(counts []int64, total int64, p float64) float64 {
+func (h *Histogram) percentileFromBuckets(counts []int64, total int64, p float64) float64 {
 	target := float64(total) * p
 	var cumulative float64
 
 	for i, c := range counts {
 		cumulative += float64(c)
 		if cumulative >= target {
-			loUs := bucketLowerUs(i)
-			hiUs := bucketUpperUs(i)
+			loUs := h.bucketLowerUs(i)
+			hiUs := h.bucketUpperUs(i)
 			prev := cumulative - float64(c)
 			frac := 0.0
 			if c > 0 {
@@ -103,19 +206,19 @@ func percentileFromBuckets(counts []int64, total int64, p float64) float64 {
 	}
 
 	// Should not reach here; return last bound as fallback.
-	return float64(bucketBoundsUs[numBounds-1]) / 1000.0
+	return float64(h.bounds[len(h.bounds)-1]) / 1000.0
 }
 
-func bucketLowerUs(i int) int64 {
+func (h *Histogram) bucketLowerUs(i int) int64 {
 	if i == 0 {
 		return 0
 	}
-	return bucketBoundsUs[i-1]
+	return h.bounds[i-1]
 }
 
-func bucketUpperUs(i int) int64 {
-	if i >= numBounds {
-		return bucketBoundsUs[numBounds-1] * 2 // overflow estimate
+func (h *Histogram) bucketUpperUs(i int) int64 {
+	if i >= len(h.bounds) {
+		return h.bounds[len(h.bounds)-1] * 2 // overflow estimate
 	}
-	return bucketBoundsUs[i]
+	return h.bounds[i]
 }
@@ -0,0 +1,54 @@
+package metrics
+
+// defaultHistorySize is how many snapshots are kept when not configured
+const defaultHistorySize = 3000
+
+// SetHistorySize sets how many recent snapshots History retains (0 disables
+// history). Existing history is discarded.
+func (c *Collector) SetHistorySize(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.historySize = max(n, 0)
+	c.history = nil
+	c.historyNext = 0
+}
+
+// appendHistory records a snapshot, evicting the oldest when full. Recent
+// errors are dropped: they are only sent when changed, so they would be
+// scattered and incomplete across the history.
+func (c *Collector) appendHistory(snapshot MetricsSnapshot) {
+	snapshot.RecentErrors = nil
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.latest = &snapshot
+	if c.historySize == 0 {
+		return
+	}
+	if len(c.history) < c.historySize {
+		c.history = append(c.history, snapshot)
+		return
+	}
+	c.history[c.historyNext] = snapshot
+	c.historyNext = (c.historyNext + 1) % c.historySize
+}
+
+// Latest returns the most recent snapshot, even with history disabled, or
+// nil if none has been taken since the last reset
+func (c *Collector) Latest() *MetricsSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.latest
+}
+
+// History returns the retained snapshots, oldest first
+func (c *Collector) History() []MetricsSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	history := make([]MetricsSnapshot, 0, len(c.history))
+	history = append(history, c.history[c.historyNext:]...)
+	history = append(history, c.history[:c.historyNext]...)
+	return history
+}
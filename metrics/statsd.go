@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net"
+)
+
+// Exporter pushes each metrics snapshot to an external system
+type Exporter interface {
+	Export(snapshot MetricsSnapshot)
+}
+
+// nopExporter discards snapshots (used when no exporter is configured)
+type nopExporter struct{}
+
+func (nopExporter) Export(MetricsSnapshot) {}
+
+// maxStatsDPacket keeps UDP payloads under a typical path MTU
+const maxStatsDPacket = 1432
+
+// StatsDExporter sends snapshot gauges and counters to a StatsD/DogStatsD
+// agent over UDP
+type StatsDExporter struct {
+	conn   net.Conn
+	prefix string
+	buf    bytes.Buffer
+
+	// Packets dropped since writes started failing; only the first failure
+	// and the recovery are logged, so an unreachable agent doesn't flood logs
+	failing bool
+	dropped int64
+}
+
+// NewStatsDExporter returns an exporter sending to addr (host:port), or a
+// no-op exporter if addr is empty
+func NewStatsDExporter(addr, prefix string) (Exporter, error) {
+	if addr == "" {
+		return nopExporter{}, nil
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve statsd address %q: %w", addr, err)
+	}
+	return &StatsDExporter{conn: conn, prefix: prefix}, nil
+}
+
+// Export sends the snapshot's headline metrics. It is called from a single
+// goroutine once per metrics interval.
+func (e *StatsDExporter) Export(s MetricsSnapshot) {
+	e.buf.Reset()
+
+	e.operation("reads", s.Reads)
+	e.operation("writes", s.Writes)
+	e.metric("connections.active", float64(s.Pool.ActiveConnections), "g")
+	e.metric("connections.waiting", float64(s.Pool.WaitingRequests), "g")
+	if s.Connect != nil {
+		e.metric("connect.rate", s.Connect.QPS, "g")
+		e.metric("connect.latency_p99_ms", s.Connect.LatencyP99, "g")
+	}
+
+	e.flush()
+}
+
+func (e *StatsDExporter) operation(op string, stats OperationStats) {
+	e.metric(op+".qps", stats.QPS, "g")
+	e.metric(op+".latency_p50_ms", stats.LatencyP50, "g")
+	e.metric(op+".latency_p95_ms", stats.LatencyP95, "g")
+	e.metric(op+".latency_p99_ms", stats.LatencyP99, "g")
+	e.metric(op+".latency_avg_ms", stats.LatencyAvg, "g")
+	e.metric(op+".latency_max_ms", stats.LatencyMax, "g")
+	if stats.Errors > 0 {
+		e.metric(op+".errors", float64(stats.Errors), "c")
+	}
+}
+
+// metric appends one line, flushing first if it would overflow the packet
+func (e *StatsDExporter) metric(name string, value float64, kind string) {
+	line := fmt.Sprintf("%s%s:%g|%s", e.prefix, name, value, kind)
+	if e.buf.Len() > 0 && e.buf.Len()+1+len(line) > maxStatsDPacket {
+		e.flush()
+	}
+	if e.buf.Len() > 0 {
+		e.buf.WriteByte('\n')
+	}
+	e.buf.WriteString(line)
+}
+
+func (e *StatsDExporter) flush() {
+	if e.buf.Len() == 0 {
+		return
+	}
+	_, err := e.conn.Write(e.buf.Bytes())
+	e.buf.Reset()
+
+	switch {
+	case err != nil:
+		e.dropped++
+		if !e.failing {
+			e.failing = true
+			slog.Warn("StatsD writes failing; dropping packets until they succeed", "error", err)
+		}
+	case e.failing:
+		slog.Info("StatsD writes recovered", "dropped_packets", e.dropped)
+		e.failing = false
+		e.dropped = 0
+	}
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+// TargetStats holds resource usage reported by the database host
+type TargetStats struct {
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryPercent float64 `json:"memory_percent,omitempty"`
+	MemoryBytes   int64   `json:"memory_bytes,omitempty"`
+	SampledAt     int64   `json:"sampled_at"` // Unix milliseconds
+}
+
+// TargetPoller fetches the target's current resource usage
+type TargetPoller interface {
+	Poll(ctx context.Context) (TargetStats, error)
+}
+
+// HTTPTargetPoller GETs a URL returning TargetStats as JSON
+type HTTPTargetPoller struct {
+	URL    string
+	Client *http.Client
+}
+
+// Poll implements TargetPoller
+func (p *HTTPTargetPoller) Poll(ctx context.Context) (TargetStats, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return TargetStats{}, err
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return TargetStats{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return TargetStats{}, fmt.Errorf("%s returned %s", p.URL, resp.Status)
+	}
+	var stats TargetStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return TargetStats{}, fmt.Errorf("invalid response from %s: %w", p.URL, err)
+	}
+	return stats, nil
+}
+
+// CommandTargetPoller runs a shell command that prints TargetStats as JSON
+type CommandTargetPoller struct {
+	Command string
+}
+
+// Poll implements TargetPoller
+func (p *CommandTargetPoller) Poll(ctx context.Context) (TargetStats, error) {
+	out, err := exec.CommandContext(ctx, "sh", "-c", p.Command).Output()
+	if err != nil {
+		return TargetStats{}, fmt.Errorf("target command failed: %w", err)
+	}
+	var stats TargetStats
+	if err := json.Unmarshal(bytes.TrimSpace(out), &stats); err != nil {
+		return TargetStats{}, fmt.Errorf("invalid target command output: %w", err)
+	}
+	return stats, nil
+}
+
+// NewTargetPoller returns a poller for url or command (url wins if both are
+// set), or nil if neither is configured
+func NewTargetPoller(url, command string, timeout time.Duration) TargetPoller {
+	switch {
+	case url != "":
+		return &HTTPTargetPoller{URL: url, Client: &http.Client{Timeout: timeout}}
+	case command != "":
+		return &CommandTargetPoller{Command: command}
+	}
+	return nil
+}
+
+// PollTarget polls every interval and stores each result in c until ctx is
+// done. Each poll is bounded by the interval so a slow target cannot pile up.
+func (c *Collector) PollTarget(ctx context.Context, poller TargetPoller, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		pollCtx, cancel := context.WithTimeout(ctx, interval)
+		stats, err := poller.Poll(pollCtx)
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Warn("Target poller", "error", err)
+			}
+		} else {
+			if stats.SampledAt == 0 {
+				stats.SampledAt = time.Now().UnixMilli()
+			}
+			c.mu.Lock()
+			c.targetStats = &stats
+			c.mu.Unlock()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
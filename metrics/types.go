@@ -1,5 +1,7 @@
 package metrics
 
+import "time"
+
 // MetricsSnapshot represents a point-in-time snapshot of all metrics
 type MetricsSnapshot struct {
 	Timestamp    int64          `json:"timestamp"`
@@ -8,21 +10,70 @@ type MetricsSnapshot struct {
 	Totals       TotalStats     `json:"totals"`
 	Pool         PoolStats      `json:"pool"`
 	RecentErrors []ErrorEntry   `json:"recent_errors,omitempty"`
+	WarmingUp    bool           `json:"warming_up,omitempty"` // Reads and writes not recorded yet (warmup_seconds)
+
+	// Optional sections, omitted when there is nothing to report
+	Connect            *OperationStats    `json:"connect,omitempty"` // Connection establishment (QPS = connects/sec)
+	ConnectPhases      *ConnectPhaseStats `json:"connect_phases,omitempty"`
+	ConnectionLifetime *LifetimeStats     `json:"connection_lifetime,omitempty"`
+	Explain            *ExplainStats      `json:"explain,omitempty"`
+	Visibility         *VisibilityStats   `json:"visibility,omitempty"`
+	Chaos              *ChaosStats        `json:"chaos,omitempty"`
+	Table              *TableStats        `json:"table,omitempty"`
+	WAL                *WALStats          `json:"wal,omitempty"`
+	Target             *TargetStats       `json:"target,omitempty"` // Database host CPU/memory
+
+	// Cumulative read/write errors by SQLSTATE ("none" for client-side errors
+	// such as failed dials and timeouts)
+	ErrorsByCode map[string]int64 `json:"errors_by_code,omitempty"`
+
+	// Reads broken down by named read endpoint (READ_ENDPOINTS)
+	Endpoints map[string]OperationStats `json:"endpoints,omitempty"`
+
+	// Metrics for additional named workloads, keyed by workload name
+	Workloads map[string]MetricsSnapshot `json:"workloads,omitempty"`
 }
 
 // ErrorEntry represents a single error with timestamp
 type ErrorEntry struct {
 	Timestamp int64  `json:"timestamp"`
 	Message   string `json:"message"`
+	Count     int64  `json:"count"` // Consecutive occurrences folded into this entry
 }
 
 // OperationStats holds metrics for a specific operation type (read/write)
 type OperationStats struct {
 	QPS        float64 `json:"qps"`
 	LatencyP50 float64 `json:"latency_p50_ms"`
+	LatencyP90 float64 `json:"latency_p90_ms"`
+	LatencyP95 float64 `json:"latency_p95_ms"`
 	LatencyP99 float64 `json:"latency_p99_ms"`
 	LatencyAvg float64 `json:"latency_avg_ms"`
+	LatencyMax float64 `json:"latency_max_ms"`
 	Errors     int64   `json:"errors"`
+	Retries    int64   `json:"retries,omitempty"`
+
+	// QPS smoothed with an exponentially weighted moving average across windows
+	QPSSmoothed float64 `json:"qps_smoothed"`
+
+	// Rows per second when writes are batched into transactions or multi-row
+	// inserts (QPS then counts commits or statements), or when reads fetch
+	// ranges (read_mode "range")
+	RowsPerSec float64 `json:"rows_per_sec,omitempty"`
+
+	// Operations this window that waited for a concurrency slot (saturation)
+	ConcurrencyWaits int64 `json:"concurrency_waits,omitempty"`
+
+	// Reads this window that took an ID from the recent-insert cache vs a
+	// random guess within 1..max_user_id (reads only)
+	CachedIDReads int64 `json:"cached_id_reads,omitempty"`
+	RandomIDReads int64 `json:"random_id_reads,omitempty"`
+
+	// Largest latency seen since start or the last reset
+	LatencyMaxCumulative float64 `json:"latency_max_cumulative_ms"`
+
+	// Average simulated network latency included in the window's latencies
+	InjectedLatencyAvg float64 `json:"injected_latency_avg_ms,omitempty"`
 }
 
 // TotalStats holds aggregate metrics
@@ -30,6 +81,12 @@ type TotalStats struct {
 	Queries   int64   `json:"queries"`
 	Errors    int64   `json:"errors"`
 	ErrorRate float64 `json:"error_rate"`
+	Retries   int64   `json:"retries,omitempty"`
+
+	// Estimated bytes of column values read and written (strings by length,
+	// numbers and timestamps as 8 bytes; protocol overhead excluded)
+	ReadBytes  int64 `json:"read_bytes"`
+	WriteBytes int64 `json:"write_bytes"`
 }
 
 // PoolStats holds connection pool metrics
@@ -37,4 +94,98 @@ type PoolStats struct {
 	ActiveConnections int32 `json:"active_connections"`
 	IdleConnections   int32 `json:"idle_connections,omitempty"`
 	WaitingRequests   int32 `json:"waiting_requests,omitempty"`
+
+	// Average time spent waiting for a connection slot this window
+	AcquireWaitAvg float64 `json:"acquire_wait_avg_ms,omitempty"`
+
+	// Average time spent borrowing from the shared pool (pool mode) this window
+	PoolAcquireWaitAvg float64 `json:"pool_acquire_wait_avg_ms,omitempty"`
+
+	// Connects this window that waited for a dial slot (RECONNECT_CONCURRENCY)
+	DialWaits int64 `json:"dial_waits,omitempty"`
+}
+
+// ConnectPhaseStats breaks connection establishment into sub-phases, to tell
+// network distance, TLS cost, and auth or pooler queueing apart
+type ConnectPhaseStats struct {
+	Dial    PhaseStats  `json:"dial"`
+	TLS     *PhaseStats `json:"tls,omitempty"` // Only when connections use TLS
+	Startup PhaseStats  `json:"startup"`       // Startup message through ready, including auth
+}
+
+// PhaseStats holds latency percentiles for one connect phase
+type PhaseStats struct {
+	LatencyP50 float64 `json:"latency_p50_ms"`
+	LatencyP99 float64 `json:"latency_p99_ms"`
+	LatencyAvg float64 `json:"latency_avg_ms"`
+	LatencyMax float64 `json:"latency_max_ms"`
+}
+
+// LifetimeStats summarizes how long connections stayed open (since start or reset)
+type LifetimeStats struct {
+	Count  int     `json:"count"`
+	P50Sec float64 `json:"p50_sec"`
+	P99Sec float64 `json:"p99_sec"`
+	AvgSec float64 `json:"avg_sec"`
+	MaxSec float64 `json:"max_sec"`
+}
+
+// TableStats holds dead-tuple and vacuum counters for the workload table
+type TableStats struct {
+	Table           string     `json:"table"`
+	LiveTuples      int64      `json:"live_tuples"`
+	DeadTuples      int64      `json:"dead_tuples"`
+	LastAutovacuum  *time.Time `json:"last_autovacuum,omitempty"`
+	LastAutoanalyze *time.Time `json:"last_autoanalyze,omitempty"`
+	AutovacuumCount int64      `json:"autovacuum_count"`
+	SampledAt       int64      `json:"sampled_at"` // Unix milliseconds
+
+	// Change in dead tuples per second since the previous sample (negative
+	// after a vacuum)
+	DeadTuplesPerSec float64 `json:"dead_tuples_per_sec"`
+}
+
+// WALStats holds the server's WAL generation rate from the WAL monitor
+type WALStats struct {
+	LSN         string  `json:"lsn"`
+	BytesPerSec float64 `json:"bytes_per_sec"`
+	TotalBytes  int64   `json:"total_bytes"` // Generated since monitoring began
+	SampledAt   int64   `json:"sampled_at"`  // Unix milliseconds
+}
+
+// VisibilityStats holds cross-connection read-your-writes checks: a reader on
+// another connection polls for a fresh insert until it appears. Delays are
+// measured from the writer's commit, so they include the time the probe
+// waited for a reader.
+type VisibilityStats struct {
+	Checks   int64   `json:"checks"`
+	TimedOut int64   `json:"timed_out"`
+	DelayP50 float64 `json:"delay_p50_ms"`
+	DelayP99 float64 `json:"delay_p99_ms"`
+	DelayMax float64 `json:"delay_max_ms"`
+}
+
+// ChaosStats holds chaos mode activity for the window: connections severed
+// and how long the affected workers took to reconnect (measured from the sever)
+type ChaosStats struct {
+	Severed      int64   `json:"severed"`
+	Reconnected  int64   `json:"reconnected"`
+	ReconnectP50 float64 `json:"reconnect_p50_ms"`
+	ReconnectP99 float64 `json:"reconnect_p99_ms"`
+	ReconnectMax float64 `json:"reconnect_max_ms"`
+}
+
+// ExplainStats holds server-side timings from EXPLAIN ANALYZE samples
+type ExplainStats struct {
+	Reads  PlanStats `json:"reads"`
+	Writes PlanStats `json:"writes"`
+}
+
+// PlanStats splits sampled query time into planning and execution
+type PlanStats struct {
+	Samples      int     `json:"samples"`
+	PlanningAvg  float64 `json:"planning_avg_ms"`
+	PlanningP99  float64 `json:"planning_p99_ms"`
+	ExecutionAvg float64 `json:"execution_avg_ms"`
+	ExecutionP99 float64 `json:"execution_p99_ms"`
 }